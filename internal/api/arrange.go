@@ -0,0 +1,233 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/alexl/go-fake-api/internal/idgen"
+	"github.com/alexl/go-fake-api/internal/middleware"
+	"github.com/alexl/go-fake-api/internal/models"
+	"github.com/alexl/go-fake-api/internal/storage"
+	"github.com/alexl/go-fake-api/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+// ReorderObjects меняет порядок наложения объектов и рассылает objects_reorder
+// с новыми z изменившихся объектов
+func (h *Hub) ReorderObjects(boardID string, userID int, req models.ObjectsReorderRequest) (models.ObjectsReorder, error) {
+	switch req.Action {
+	case models.ReorderFront, models.ReorderBack, models.ReorderForward, models.ReorderBackward:
+	default:
+		return models.ObjectsReorder{}, validationError(map[string][]string{
+			"action": {"action must be one of front, back, forward, backward"},
+		})
+	}
+	if err := checkObjectIDs("ids", req.IDs, 1); err != nil {
+		return models.ObjectsReorder{}, err
+	}
+
+	z, err := h.storage.ReorderObjects(boardID, userID, req.IDs, req.Action)
+	if err != nil {
+		return models.ObjectsReorder{}, storageOpError(err)
+	}
+
+	result := models.ObjectsReorder{Z: z}
+	if len(z) > 0 {
+		h.touch(boardID)
+		h.Broadcast(models.WSMessage{Type: "objects_reorder", BoardID: boardID, Payload: result})
+	}
+	return result, nil
+}
+
+// GroupObjects объединяет объекты в группу и рассылает objects_batch с группой и ее объектами
+func (h *Hub) GroupObjects(boardID string, userID int, req models.ObjectsGroupRequest) (models.ObjectsBatch, error) {
+	generated := req.ID == ""
+	if generated {
+		req.ID = idgen.ObjectID()
+	} else if len(req.ID) > models.MaxObjectIDLen {
+		return models.ObjectsBatch{}, validationError(map[string][]string{
+			"id": {fmt.Sprintf("id must be at most %d characters", models.MaxObjectIDLen)},
+		})
+	}
+	if err := checkObjectIDs("children", req.Children, 2); err != nil {
+		return models.ObjectsBatch{}, err
+	}
+
+	grouped, err := h.storage.GroupObjects(boardID, userID, req.ID, req.Children)
+	// Выданный ID мог уже занять объект, созданный с ID из запроса
+	for generated && errors.Is(err, storage.ErrObjectExists) {
+		req.ID = idgen.ObjectID()
+		grouped, err = h.storage.GroupObjects(boardID, userID, req.ID, req.Children)
+	}
+	if err != nil {
+		return models.ObjectsBatch{}, storageOpError(err)
+	}
+
+	result := models.ObjectsBatch{Updates: grouped, Deletes: []string{}}
+	h.touch(boardID)
+	h.Broadcast(models.WSMessage{Type: "objects_batch", BoardID: boardID, Payload: result})
+	return result, nil
+}
+
+// UngroupObjects расформировывает группу и рассылает objects_batch: удаление группы
+// и обновление ее объектов
+func (h *Hub) UngroupObjects(boardID string, userID int, groupID string) (models.ObjectsBatch, error) {
+	changed, err := h.storage.UngroupObjects(boardID, userID, groupID)
+	if err != nil {
+		return models.ObjectsBatch{}, storageOpError(err)
+	}
+
+	result := models.ObjectsBatch{Updates: changed, Deletes: []string{groupID}}
+	h.touch(boardID)
+	h.Broadcast(models.WSMessage{Type: "objects_batch", BoardID: boardID, Payload: result})
+	return result, nil
+}
+
+// LockObject закрепляет объект или снимает закрепление и рассылает object_update.
+// Закреплять может только владелец доски; в отличие от фокуса закрепление
+// действует, пока владелец его не снимет.
+func (h *Hub) LockObject(boardID string, userID int, req models.ObjectLockRequest) (models.BoardObject, error) {
+	if err := ownerOnly(h.storage, boardID, userID, "only owner can lock objects"); err != nil {
+		return models.BoardObject{}, err
+	}
+
+	obj, err := h.storage.SetObjectLocked(boardID, req.ID, req.Locked)
+	if err != nil {
+		return obj, storageOpError(err)
+	}
+
+	h.touch(boardID)
+	h.Broadcast(models.WSMessage{Type: "object_update", BoardID: boardID, Payload: obj})
+	return obj, nil
+}
+
+// checkObjectIDs проверяет список ID объектов операции: не меньше min, без повторов
+func checkObjectIDs(field string, ids []string, min int) error {
+	if len(ids) < min {
+		return validationError(map[string][]string{field: {fmt.Sprintf("%s must contain at least %d object ids", field, min)}})
+	}
+	if len(ids) > MaxBatchSize {
+		return validationError(map[string][]string{field: {fmt.Sprintf("%s can not contain more than %d object ids", field, MaxBatchSize)}})
+	}
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			return validationError(map[string][]string{field: {"duplicate object id: " + id}})
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// ownerOnly проверяет, что пользователь - владелец доски
+func ownerOnly(s storage.Storage, boardID string, userID int, message string) error {
+	role, _ := s.GetBoardRole(boardID, userID)
+	if role == "" {
+		return opError(ErrCodeNotFound, "board not found")
+	}
+	if role != models.RoleOwner {
+		return opError(ErrCodeForbidden, message)
+	}
+	return nil
+}
+
+// ReorderBoardObjects меняет порядок наложения объектов
+func ReorderBoardObjects(hub *Hub, s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		boardID := mux.Vars(r)["board_id"]
+
+		if err := objectAccess(s, boardID, user.ID, true); err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		var req models.ObjectsReorderRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.SendError(w, http.StatusBadRequest, "invalid request body", nil)
+			return
+		}
+
+		result, err := hub.ReorderObjects(boardID, user.ID, req)
+		if err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		utils.SendSuccess(w, http.StatusOK, "objects reordered", result)
+	}
+}
+
+// GroupBoardObjects объединяет объекты в группу
+func GroupBoardObjects(hub *Hub, s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		boardID := mux.Vars(r)["board_id"]
+
+		if err := objectAccess(s, boardID, user.ID, true); err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		var req models.ObjectsGroupRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.SendError(w, http.StatusBadRequest, "invalid request body", nil)
+			return
+		}
+
+		result, err := hub.GroupObjects(boardID, user.ID, req)
+		if err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		utils.SendSuccess(w, http.StatusCreated, "objects grouped", result)
+	}
+}
+
+// UngroupBoardObject расформировывает группу, объекты остаются на доске
+func UngroupBoardObject(hub *Hub, s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		vars := mux.Vars(r)
+		boardID := vars["board_id"]
+
+		if err := objectAccess(s, boardID, user.ID, true); err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		result, err := hub.UngroupObjects(boardID, user.ID, vars["object_id"])
+		if err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		utils.SendSuccess(w, http.StatusOK, "group removed", result)
+	}
+}
+
+// LockBoardObject закрепляет объект или снимает закрепление, только владелец доски
+func LockBoardObject(hub *Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		vars := mux.Vars(r)
+
+		var req models.ObjectLockRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.SendError(w, http.StatusBadRequest, "invalid request body", nil)
+			return
+		}
+		req.ID = vars["object_id"]
+
+		obj, err := hub.LockObject(vars["board_id"], user.ID, req)
+		if err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		utils.SendSuccess(w, http.StatusOK, "object updated", obj)
+	}
+}
@@ -0,0 +1,229 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/alexl/go-fake-api/internal/assets"
+	"github.com/alexl/go-fake-api/internal/clock"
+	"github.com/alexl/go-fake-api/internal/idgen"
+	"github.com/alexl/go-fake-api/internal/middleware"
+	"github.com/alexl/go-fake-api/internal/models"
+	"github.com/alexl/go-fake-api/internal/storage"
+	"github.com/alexl/go-fake-api/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+// assetCacheControl файлы изображений не меняются, новая загрузка получает новый ID
+const assetCacheControl = "public, max-age=31536000, immutable"
+
+// UploadAsset принимает изображение в поле file формы multipart/form-data,
+// сохраняет его вместе с уменьшенными вариантами и возвращает ссылки на них
+func UploadAsset(s storage.Storage, files *assets.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		boardID := mux.Vars(r)["board_id"]
+
+		if err := objectAccess(s, boardID, user.ID, true); err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		// Запас сверх лимита файла - на границы и заголовки частей формы
+		r.Body = http.MaxBytesReader(w, r.Body, models.MaxAssetSize+1<<20)
+		data, err := readUploadedFile(r)
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge) || len(data) > models.MaxAssetSize:
+			utils.SendError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("file must be at most %d MB", models.MaxAssetSize>>20), nil)
+			return
+		case err != nil:
+			utils.SendError(w, http.StatusBadRequest, "request must be multipart/form-data", nil)
+			return
+		case data == nil:
+			utils.RespondWithValidationError(w, map[string][]string{"file": {"file is required"}})
+			return
+		}
+
+		img, err := assets.Process(data)
+		switch {
+		case errors.Is(err, assets.ErrUnsupportedType):
+			utils.SendError(w, http.StatusUnsupportedMediaType, err.Error(), nil)
+			return
+		case err != nil:
+			utils.RespondWithValidationError(w, map[string][]string{"file": {err.Error()}})
+			return
+		}
+
+		id := idgen.Hash()
+		url := assetPathPrefix(r) + "/assets/" + id
+		asset := &models.Asset{
+			ID:         id,
+			BoardID:    boardID,
+			UploaderID: user.ID,
+			MimeType:   img.MimeType,
+			Size:       int64(len(img.Data)),
+			Width:      img.Width,
+			Height:     img.Height,
+			URL:        url,
+			Variants:   []models.AssetVariant{},
+			CreatedAt:  clock.Now(),
+		}
+		stored := map[string][]byte{"original" + assets.Extension(img.MimeType): img.Data}
+		for _, variant := range img.Variants {
+			stored[variant.Name+assets.Extension(variant.MimeType)] = variant.Data
+			asset.Variants = append(asset.Variants, models.AssetVariant{
+				Name:     variant.Name,
+				MimeType: variant.MimeType,
+				Size:     int64(len(variant.Data)),
+				Width:    variant.Width,
+				Height:   variant.Height,
+				URL:      url + "/" + variant.Name,
+			})
+		}
+
+		if err := files.Save(id, stored); err != nil {
+			log.Printf("asset %s: %v", id, err)
+			utils.SendError(w, http.StatusInternalServerError, "could not store file", nil)
+			return
+		}
+		if err := s.CreateAsset(asset); err != nil {
+			files.Remove(id)
+			utils.SendError(w, http.StatusNotFound, "board not found", nil)
+			return
+		}
+
+		utils.SendSuccess(w, http.StatusCreated, "asset uploaded", asset)
+	}
+}
+
+// readUploadedFile читает поле file формы; nil, если поля нет
+func readUploadedFile(r *http.Request) ([]byte, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == "file" {
+			// Лишний байт показывает, что файл больше лимита
+			return io.ReadAll(io.LimitReader(part, models.MaxAssetSize+1))
+		}
+	}
+}
+
+// assetPathPrefix базовый путь API из пути запроса .../boards/{board_id}/assets
+func assetPathPrefix(r *http.Request) string {
+	if i := strings.Index(r.URL.Path, "/boards/"); i > 0 {
+		return r.URL.Path[:i]
+	}
+	return ""
+}
+
+// GetBoardAssets возвращает изображения, загруженные на доску
+func GetBoardAssets(s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		boardID := mux.Vars(r)["board_id"]
+
+		if err := objectAccess(s, boardID, user.ID, false); err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		list, err := s.GetBoardAssets(boardID)
+		if err != nil {
+			utils.SendError(w, http.StatusNotFound, "board not found", nil)
+			return
+		}
+
+		utils.SendSuccess(w, http.StatusOK, "success", list)
+	}
+}
+
+// ServeAsset отдает файл изображения или его вариант без авторизации, чтобы картинки
+// открывались в <img> и на публичных досках. Если вариант не нужен (изображение
+// меньше его размера), отдается оригинал.
+func ServeAsset(s storage.Storage, files *assets.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		asset, err := s.GetAsset(vars["asset_id"])
+		if err != nil {
+			utils.SendError(w, http.StatusNotFound, "asset not found", nil)
+			return
+		}
+
+		name, mimeType, tag := "original"+assets.Extension(asset.MimeType), asset.MimeType, "original"
+		if variantName := vars["variant"]; variantName != "" {
+			known := false
+			for _, size := range models.AssetVariantSizes {
+				known = known || size.Name == variantName
+			}
+			if !known {
+				utils.SendError(w, http.StatusNotFound, "variant not found", nil)
+				return
+			}
+			for _, variant := range asset.Variants {
+				if variant.Name == variantName {
+					name, mimeType, tag = variant.Name+assets.Extension(variant.MimeType), variant.MimeType, variant.Name
+				}
+			}
+		}
+
+		f, err := files.Open(asset.ID, name)
+		if err != nil {
+			utils.SendError(w, http.StatusNotFound, "asset not found", nil)
+			return
+		}
+		defer f.Close()
+
+		w.Header().Set("Content-Type", mimeType)
+		w.Header().Set("Cache-Control", assetCacheControl)
+		w.Header().Set("ETag", `"`+asset.ID+"-"+tag+`"`)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		http.ServeContent(w, r, "", asset.CreatedAt, f)
+	}
+}
+
+// collectAssets удаляет изображения старше grace, на которые не ссылается ни один объект
+func collectAssets(s storage.Storage, files *assets.Store, grace time.Duration) []string {
+	collected := []string{}
+	for _, asset := range s.CollectAssets(clock.Now().Add(-grace)) {
+		if err := files.Remove(asset.ID); err != nil {
+			log.Printf("asset %s: %v", asset.ID, err)
+		}
+		collected = append(collected, asset.ID)
+	}
+	return collected
+}
+
+// RunAssetCollector периодически удаляет неиспользуемые изображения.
+// Свежие загрузки живут не меньше grace, чтобы клиент успел сослаться на них.
+func RunAssetCollector(s storage.Storage, files *assets.Store, interval, grace time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if collected := collectAssets(s, files, grace); len(collected) > 0 {
+			log.Printf("Collected %d unused assets", len(collected))
+		}
+	}
+}
+
+// CollectAssets немедленно удаляет неиспользуемые изображения, для тестов
+func CollectAssets(s storage.Storage, files *assets.Store, grace time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		result := models.AssetCollection{Collected: collectAssets(s, files, grace)}
+		utils.SendSuccess(w, http.StatusOK, "success", result)
+	}
+}
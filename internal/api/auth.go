@@ -5,6 +5,8 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/alexl/go-fake-api/internal/clock"
+	"github.com/alexl/go-fake-api/internal/middleware"
 	"github.com/alexl/go-fake-api/internal/models"
 	"github.com/alexl/go-fake-api/internal/storage"
 	"github.com/alexl/go-fake-api/internal/utils"
@@ -14,6 +16,9 @@ import (
 
 var jwtSecret = []byte("your-secret-key-change-in-production")
 
+// tokenTTL время жизни токена
+const tokenTTL = time.Hour * 24 * 7 // 7 дней
+
 // Registration обработчик регистрации
 func Registration(store storage.Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -50,7 +55,7 @@ func Registration(store storage.Storage) http.HandlerFunc {
 			Name:      req.Name,
 			Email:     req.Email,
 			Password:  string(hashedPassword),
-			CreatedAt: time.Now(),
+			CreatedAt: clock.Now(),
 		}
 
 		if err := store.CreateUser(user); err != nil {
@@ -58,6 +63,12 @@ func Registration(store storage.Storage) http.HandlerFunc {
 			return
 		}
 
+		// Применяем приглашения, отправленные на этот email до регистрации
+		if _, err := store.ApplyEmailInvites(user, clock.Now()); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to apply invites", nil)
+			return
+		}
+
 		// Ответ
 		response := map[string]interface{}{
 			"data": map[string]interface{}{
@@ -103,10 +114,13 @@ func Authorization(store storage.Storage) http.HandlerFunc {
 		}
 
 		// Генерация JWT токена
+		now := clock.Now()
+		expiresAt := now.Add(tokenTTL)
 		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 			"user_id": user.ID,
 			"email":   user.Email,
-			"exp":     time.Now().Add(time.Hour * 24 * 7).Unix(), // 7 дней
+			"iat":     now.Unix(),
+			"exp":     expiresAt.Unix(),
 		})
 
 		tokenString, err := token.SignedString(jwtSecret)
@@ -116,7 +130,7 @@ func Authorization(store storage.Storage) http.HandlerFunc {
 		}
 
 		// Сохранение токена
-		if err := store.UpdateUserToken(user.ID, tokenString); err != nil {
+		if err := store.UpdateUserToken(user.ID, tokenString, expiresAt); err != nil {
 			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to save token", nil)
 			return
 		}
@@ -138,15 +152,17 @@ func Authorization(store storage.Storage) http.HandlerFunc {
 }
 
 // Logout обработчик выхода
-func Logout(store storage.Storage) http.HandlerFunc {
+func Logout(hub *Hub, store storage.Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		user := r.Context().Value("user").(*models.User)
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
 		
 		// Очистка токена
-		if err := store.UpdateUserToken(user.ID, ""); err != nil {
+		if err := store.UpdateUserToken(user.ID, "", time.Time{}); err != nil {
 			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to logout", nil)
 			return
 		}
+		// Открытые сокеты со старым токеном закрываются сразу
+		hub.RecheckUser(user.ID)
 
 		w.WriteHeader(http.StatusNoContent)
 	}
@@ -0,0 +1,156 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alexl/go-fake-api/internal/models"
+	"github.com/alexl/go-fake-api/internal/storage"
+)
+
+// HubBenchOptions параметры нагрузочного теста Hub
+type HubBenchOptions struct {
+	Boards   int    // Число досок
+	Clients  int    // Число клиентов, распределяются по доскам поровну
+	Messages int    // Сколько всего рассылок сделать
+	Senders  int    // Сколько горутин рассылают одновременно
+	Format   string // Формат сообщений клиентов: json или msgpack
+}
+
+// HubBenchResult результат нагрузочного теста Hub
+type HubBenchResult struct {
+	Format              string  `json:"format"`
+	Boards              int     `json:"boards"`
+	Clients             int     `json:"clients"`
+	Messages            int     `json:"messages"`
+	Deliveries          int64   `json:"deliveries"`
+	BytesDelivered      int64   `json:"bytes_delivered"`
+	DroppedClients      int64   `json:"dropped_clients"`
+	Duration            string  `json:"duration"`
+	MessagesPerSecond   float64 `json:"messages_per_second"`
+	DeliveriesPerSecond float64 `json:"deliveries_per_second"`
+}
+
+// BenchmarkHub подключает к Hub клиентов без сети и замеряет, как быстро
+// рассылки доходят до всех клиентов досок
+func BenchmarkHub(config HubConfig, opts HubBenchOptions) (HubBenchResult, error) {
+	if opts.Boards < 1 || opts.Clients < opts.Boards || opts.Messages < 1 || opts.Senders < 1 {
+		return HubBenchResult{}, errors.New("boards, messages and senders must be positive and every board needs a client")
+	}
+	if opts.Format == "" {
+		opts.Format = FormatJSON
+	}
+	if opts.Format != FormatJSON && opts.Format != FormatMsgpack {
+		return HubBenchResult{}, errors.New("format must be json or msgpack")
+	}
+
+	// Права клиентов в тесте не перепроверяются, Run не запускаем
+	hub := NewHub(storage.NewMemoryStorage(), config)
+
+	var received, receivedBytes atomic.Int64
+	var readers sync.WaitGroup
+	clients := make([]*Client, opts.Clients)
+	perBoard := make([]int64, opts.Boards)
+	for i := range clients {
+		board := i % opts.Boards
+		perBoard[board]++
+		clients[i] = &Client{
+			Hub:     hub,
+			Send:    make(chan []byte, config.SendBufferSize),
+			UserID:  i + 1,
+			BoardID: fmt.Sprintf("bench-%d", board),
+			Format:  opts.Format,
+		}
+
+		readers.Add(1)
+		go func(c *Client) {
+			defer readers.Done()
+			// Общий счетчик обновляем пачками, иначе тысячи читателей упираются в него, а не в Hub
+			var n, size int64
+			for message := range c.Send {
+				n++
+				size += int64(len(message))
+				if len(c.Send) == 0 {
+					received.Add(n)
+					receivedBytes.Add(size)
+					n, size = 0, 0
+				}
+			}
+			received.Add(n)
+			receivedBytes.Add(size)
+		}(clients[i])
+	}
+
+	// Каждое подключение рассылает presence всем, кто уже на доске: k клиентов дают k(k+1)/2
+	var presence int64
+	for _, k := range perBoard {
+		presence += k * (k + 1) / 2
+	}
+	for _, c := range clients {
+		hub.register(c)
+	}
+	waitDeliveries(&received, presence)
+	received.Store(0)
+	receivedBytes.Store(0)
+
+	var expected int64
+	for i := 0; i < opts.Messages; i++ {
+		expected += perBoard[i%opts.Boards]
+	}
+
+	start := time.Now()
+	var senders sync.WaitGroup
+	for s := 0; s < opts.Senders; s++ {
+		senders.Add(1)
+		go func(s int) {
+			defer senders.Done()
+			for i := s; i < opts.Messages; i += opts.Senders {
+				hub.Broadcast(models.WSMessage{
+					Type:    "object_update",
+					BoardID: fmt.Sprintf("bench-%d", i%opts.Boards),
+					Payload: models.BoardObject{ID: fmt.Sprintf("obj-%d", i), Type: "rect", X: float64(i), Y: float64(i)},
+				})
+			}
+		}(s)
+	}
+	senders.Wait()
+	waitDeliveries(&received, expected)
+	duration := time.Since(start)
+	deliveries, bytesDelivered := received.Load(), receivedBytes.Load()
+
+	for _, c := range clients {
+		hub.unregister(c)
+	}
+	readers.Wait()
+
+	seconds := duration.Seconds()
+	return HubBenchResult{
+		Format:              opts.Format,
+		Boards:              opts.Boards,
+		Clients:             opts.Clients,
+		Messages:            opts.Messages,
+		Deliveries:          deliveries,
+		BytesDelivered:      bytesDelivered,
+		DroppedClients:      hub.metrics.droppedSlowConsumers.Load(),
+		Duration:            duration.String(),
+		MessagesPerSecond:   float64(opts.Messages) / seconds,
+		DeliveriesPerSecond: float64(deliveries) / seconds,
+	}, nil
+}
+
+// waitDeliveries ждет, пока клиенты получат expected сообщений.
+// Если медленных клиентов отключили, счетчик не дойдет до цели: выходим, когда он перестал расти.
+func waitDeliveries(received *atomic.Int64, expected int64) {
+	last, idle := received.Load(), 0
+	for received.Load() < expected && idle < 100 {
+		time.Sleep(time.Millisecond)
+		if current := received.Load(); current != last {
+			last, idle = current, 0
+		} else {
+			idle++
+		}
+	}
+}
@@ -2,10 +2,12 @@ package api
 
 import (
 	"encoding/json"
-	"fmt"
 	"net/http"
-	"time"
+	"strconv"
+	"strings"
 
+	"github.com/alexl/go-fake-api/internal/clock"
+	"github.com/alexl/go-fake-api/internal/idgen"
 	"github.com/alexl/go-fake-api/internal/middleware"
 	"github.com/alexl/go-fake-api/internal/models"
 	"github.com/alexl/go-fake-api/internal/storage"
@@ -13,7 +15,7 @@ import (
 	"github.com/gorilla/mux"
 )
 
-// CreateBoard создает новую доску
+// CreateBoard создает новую доску, пустую или копией шаблона (template_id)
 func CreateBoard(s storage.Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		user := r.Context().Value(middleware.UserContextKey).(*models.User)
@@ -24,20 +26,33 @@ func CreateBoard(s storage.Storage) http.HandlerFunc {
 			return
 		}
 
-		boardID := fmt.Sprintf("board-%d", time.Now().UnixNano())
-		hash := fmt.Sprintf("%x", time.Now().UnixNano())
-
 		board := &models.Board{
-			ID:        boardID,
-			Hash:      hash,
-			Name:      req.Name,
-			OwnerID:   user.ID,
-			IsPublic:  req.IsPublic,
-			Objects:   make(map[string]models.BoardObject),
-			CreatedAt: time.Now(),
+			ID:          idgen.BoardID(),
+			Hash:        idgen.Hash(),
+			Name:        req.Name,
+			OwnerID:     user.ID,
+			IsPublic:    req.IsPublic,
+			LinkSharing: req.LinkSharing,
+			Objects:     make(map[string]models.BoardObject),
+			CreatedAt:   clock.Now(),
 		}
 
-		if err := s.CreateBoard(board); err != nil {
+		// Доска из шаблона получает копию его объектов и тегов
+		var err error
+		if req.TemplateID != "" {
+			template, lookupErr := s.GetBoardByID(req.TemplateID)
+			if lookupErr != nil || !templateVisible(s, template, user.ID) {
+				utils.SendError(w, http.StatusNotFound, "template not found", nil)
+				return
+			}
+			if strings.TrimSpace(board.Name) == "" {
+				board.Name = template.Name
+			}
+			err = copyBoard(s, template, board)
+		} else {
+			err = s.CreateBoard(board)
+		}
+		if err != nil {
 			utils.SendError(w, http.StatusInternalServerError, "could not create board", nil)
 			return
 		}
@@ -46,36 +61,61 @@ func CreateBoard(s storage.Storage) http.HandlerFunc {
 	}
 }
 
-// GetUserBoards возвращает доски, к которым у пользователя есть доступ
+// GetUserBoards возвращает страницу досок, к которым у пользователя есть доступ.
+// По умолчанию сначала недавно измененные.
 func GetUserBoards(s storage.Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		user := r.Context().Value(middleware.UserContextKey).(*models.User)
 
-		boards, err := s.GetUserBoards(user.ID)
+		q, validationErrors := boardQuery(r, models.BoardSortActivity)
+		if len(validationErrors) > 0 {
+			utils.RespondWithValidationError(w, validationErrors)
+			return
+		}
+		q.UserID = user.ID
+
+		list, err := s.ListBoards(q)
 		if err != nil {
 			utils.SendError(w, http.StatusInternalServerError, "could not fetch boards", nil)
 			return
 		}
 
-		utils.SendSuccess(w, http.StatusOK, "success", boards)
+		utils.SendList(w, http.StatusOK, "success", list.Boards, listMeta(q, list))
 	}
 }
 
-// GetPublicBoards возвращает список публичных досок
+// GetPublicBoards возвращает страницу публичных досок, по умолчанию самые популярные.
+// Фильтр ownership работает, если передан токен пользователя.
 func GetPublicBoards(s storage.Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		boards, err := s.GetPublicBoards()
+		q, validationErrors := boardQuery(r, models.BoardSortLikes)
+		if len(validationErrors) > 0 {
+			utils.RespondWithValidationError(w, validationErrors)
+			return
+		}
+		q.Public = true
+
+		if q.Ownership != "" || q.Favorite || q.FolderID != nil {
+			user := optionalUser(s, r)
+			if user == nil {
+				utils.SendError(w, http.StatusUnauthorized, "ownership, favorite and folder filters require authorization", nil)
+				return
+			}
+			q.UserID = user.ID
+		}
+
+		list, err := s.ListBoards(q)
 		if err != nil {
 			utils.SendError(w, http.StatusInternalServerError, "could not fetch public boards", nil)
 			return
 		}
 
-		utils.SendSuccess(w, http.StatusOK, "success", boards)
+		utils.SendList(w, http.StatusOK, "success", list.Boards, listMeta(q, list))
 	}
 }
 
 // ShareBoard предоставляет доступ к доске
-func ShareBoard(s storage.Storage) http.HandlerFunc {
+func ShareBoard(hub *Hub, s storage.Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		user := r.Context().Value(middleware.UserContextKey).(*models.User)
 		vars := mux.Vars(r)
@@ -98,21 +138,116 @@ func ShareBoard(s storage.Storage) http.HandlerFunc {
 			return
 		}
 
+		if req.Role == "" {
+			req.Role = models.RoleEditor
+		}
+		if validationErrors := utils.ValidateShare(req); len(validationErrors) > 0 {
+			utils.RespondWithValidationError(w, validationErrors)
+			return
+		}
+
 		recipient, err := s.GetUserByEmail(req.Email)
 		if err != nil {
-			utils.SendError(w, http.StatusNotFound, "user with this email not found", nil)
+			// Незарегистрированному пользователю доступ выдается при регистрации
+			invite := &models.BoardInvite{
+				Token:     idgen.Hash(),
+				BoardID:   boardID,
+				Role:      req.Role,
+				Email:     req.Email,
+				CreatedBy: user.ID,
+				CreatedAt: clock.Now(),
+				MaxUses:   1,
+			}
+			if err := s.CreateInvite(invite); err != nil {
+				utils.SendError(w, http.StatusInternalServerError, "could not share board", nil)
+				return
+			}
+			utils.SendSuccess(w, http.StatusAccepted, "invite pending registration", invite)
 			return
 		}
 
-		if err := s.AddBoardAccess(boardID, recipient.ID); err != nil {
+		if recipient.ID == board.OwnerID {
+			utils.SendError(w, http.StatusBadRequest, "owner already has access", nil)
+			return
+		}
+
+		if err := s.AddBoardAccess(boardID, recipient.ID, req.Role); err != nil {
 			utils.SendError(w, http.StatusInternalServerError, "could not share board", nil)
 			return
 		}
 
+		hub.Notify(models.Notification{
+			UserID:    recipient.ID,
+			Type:      models.NotificationBoardShared,
+			BoardID:   boardID,
+			BoardName: board.Name,
+			ActorID:   user.ID,
+			ActorName: user.Name,
+			Role:      req.Role,
+		})
+
 		utils.SendSuccess(w, http.StatusOK, "board shared successfully", nil)
 	}
 }
 
+// GetBoardMembers возвращает участников доски с их ролями
+func GetBoardMembers(s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		vars := mux.Vars(r)
+		boardID := vars["board_id"]
+
+		hasAccess, _ := s.HasBoardAccess(boardID, user.ID)
+		if !hasAccess {
+			utils.SendError(w, http.StatusNotFound, "board not found", nil)
+			return
+		}
+
+		members, err := s.GetBoardMembers(boardID)
+		if err != nil {
+			utils.SendError(w, http.StatusNotFound, "board not found", nil)
+			return
+		}
+
+		utils.SendSuccess(w, http.StatusOK, "success", members)
+	}
+}
+
+// UnshareBoard забирает у пользователя доступ к доске.
+// Открытые WebSocket-соединения пользователя закрываются при следующей проверке прав.
+func UnshareBoard(hub *Hub, s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		vars := mux.Vars(r)
+		boardID := vars["board_id"]
+
+		board, err := s.GetBoardByID(boardID)
+		if err != nil {
+			utils.SendError(w, http.StatusNotFound, "board not found", nil)
+			return
+		}
+
+		if board.OwnerID != user.ID {
+			utils.SendError(w, http.StatusForbidden, "only owner can unshare board", nil)
+			return
+		}
+
+		userID, err := strconv.Atoi(vars["user_id"])
+		if err != nil {
+			utils.SendError(w, http.StatusBadRequest, "invalid user id", nil)
+			return
+		}
+
+		if err := s.RemoveBoardAccess(boardID, userID); err != nil {
+			utils.SendError(w, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+		hub.RecheckBoard(boardID)
+
+		utils.SendSuccess(w, http.StatusOK, "access removed", nil)
+	}
+}
+
 // GetBoardByHash возвращает публичную информацию о доске
 func GetBoardByHash(s storage.Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -125,22 +260,103 @@ func GetBoardByHash(s storage.Storage) http.HandlerFunc {
 			return
 		}
 
+		// Приватная доска без доступа по ссылке неотличима от несуществующей
+		if !board.IsPublic && !board.LinkSharing {
+			utils.SendError(w, http.StatusNotFound, "board not found", nil)
+			return
+		}
+
 		utils.SendSuccess(w, http.StatusOK, "success", board)
 	}
 }
 
+// RegenerateBoardLink выдает доске новый хеш, старая ссылка перестает работать
+func RegenerateBoardLink(hub *Hub, s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		vars := mux.Vars(r)
+		boardID := vars["board_id"]
+
+		board, err := s.GetBoardByID(boardID)
+		if err != nil {
+			utils.SendError(w, http.StatusNotFound, "board not found", nil)
+			return
+		}
+
+		if board.OwnerID != user.ID {
+			utils.SendError(w, http.StatusForbidden, "only owner can regenerate link", nil)
+			return
+		}
+
+		if err := s.UpdateBoardHash(boardID, idgen.Hash()); err != nil {
+			utils.SendError(w, http.StatusInternalServerError, "could not regenerate link", nil)
+			return
+		}
+		hub.RecheckBoard(boardID)
+
+		utils.SendSuccess(w, http.StatusOK, "link regenerated", board)
+	}
+}
+
+// SetBoardLink включает или выключает доступ к доске по ссылке
+func SetBoardLink(hub *Hub, s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		vars := mux.Vars(r)
+		boardID := vars["board_id"]
+
+		board, err := s.GetBoardByID(boardID)
+		if err != nil {
+			utils.SendError(w, http.StatusNotFound, "board not found", nil)
+			return
+		}
+
+		if board.OwnerID != user.ID {
+			utils.SendError(w, http.StatusForbidden, "only owner can change link sharing", nil)
+			return
+		}
+
+		var req models.BoardLinkRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.SendError(w, http.StatusBadRequest, "invalid request body", nil)
+			return
+		}
+
+		if err := s.SetBoardLinkSharing(boardID, req.Enabled); err != nil {
+			utils.SendError(w, http.StatusInternalServerError, "could not update link sharing", nil)
+			return
+		}
+		hub.RecheckBoard(boardID)
+
+		utils.SendSuccess(w, http.StatusOK, "link sharing updated", board)
+	}
+}
+
 // LikeBoard ставит лайк доске
-func LikeBoard(s storage.Storage) http.HandlerFunc {
+func LikeBoard(hub *Hub, s storage.Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		user := r.Context().Value(middleware.UserContextKey).(*models.User)
 		vars := mux.Vars(r)
 		boardID := vars["board_id"]
 
-		if err := s.LikeBoard(boardID, user.ID); err != nil {
+		liked, err := s.LikeBoard(boardID, user.ID)
+		if err != nil {
 			utils.SendError(w, http.StatusInternalServerError, "could not process like", nil)
 			return
 		}
 
+		// Владельца уведомляем о новом лайке, но не о снятом и не о собственном
+		if board, err := s.GetBoardByID(boardID); err == nil && liked && board.OwnerID != user.ID {
+			hub.Notify(models.Notification{
+				UserID:    board.OwnerID,
+				Type:      models.NotificationBoardLiked,
+				BoardID:   boardID,
+				BoardName: board.Name,
+				ActorID:   user.ID,
+				ActorName: user.Name,
+			})
+		}
+
 		utils.SendSuccess(w, http.StatusOK, "success", nil)
 	}
 }
@@ -0,0 +1,559 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/alexl/go-fake-api/internal/clock"
+	"github.com/alexl/go-fake-api/internal/middleware"
+	"github.com/alexl/go-fake-api/internal/models"
+	"github.com/alexl/go-fake-api/internal/storage"
+	"github.com/alexl/go-fake-api/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+// mentionSnippetLength сколько символов сообщения попадает в уведомление об упоминании
+const mentionSnippetLength = 200
+
+// PostChatMessage сохраняет сообщение чата доски и рассылает его участникам
+func (h *Hub) PostChatMessage(boardID string, userID int, userName string, text string) (models.ChatMessage, error) {
+	text, err := messageText(text)
+	if err != nil {
+		return models.ChatMessage{}, err
+	}
+
+	msg := models.ChatMessage{
+		BoardID:    boardID,
+		AuthorID:   userID,
+		AuthorName: userName,
+		Text:       text,
+		Mentions:   h.mentions(boardID, userID, text),
+		CreatedAt:  clock.Now(),
+	}
+	if err := h.storage.CreateChatMessage(&msg); err != nil {
+		return msg, storageOpError(err)
+	}
+
+	h.touch(boardID)
+	h.Broadcast(models.WSMessage{Type: "chat_message", BoardID: boardID, Payload: msg})
+	h.notifyMentions(msg.Mentions, nil, models.Notification{
+		BoardID:   boardID,
+		ActorID:   userID,
+		ActorName: userName,
+		MessageID: msg.ID,
+		Text:      text,
+	})
+	return msg, nil
+}
+
+// EditChatMessage меняет текст сообщения. Править можно только свои сообщения.
+func (h *Hub) EditChatMessage(boardID string, userID int, edit models.MessageEdit) (models.ChatMessage, error) {
+	text, err := messageText(edit.Text)
+	if err != nil {
+		return models.ChatMessage{}, err
+	}
+
+	msg, err := h.storage.GetChatMessage(boardID, edit.ID)
+	if err != nil {
+		return msg, storageOpError(err)
+	}
+	if msg.AuthorID != userID {
+		return msg, opError(ErrCodeForbidden, "only author can edit message")
+	}
+
+	previous := msg.Mentions
+	msg, err = h.storage.UpdateChatMessage(boardID, edit.ID, text, h.mentions(boardID, userID, text), clock.Now())
+	if err != nil {
+		return msg, storageOpError(err)
+	}
+
+	h.Broadcast(models.WSMessage{Type: "chat_edit", BoardID: boardID, Payload: msg})
+	h.notifyMentions(msg.Mentions, previous, models.Notification{
+		BoardID:   boardID,
+		ActorID:   userID,
+		ActorName: msg.AuthorName,
+		MessageID: msg.ID,
+		Text:      text,
+	})
+	return msg, nil
+}
+
+// DeleteChatMessage удаляет сообщение. Удалить можно свое сообщение, владелец доски - любое.
+func (h *Hub) DeleteChatMessage(boardID string, userID int, id int) (models.MessageDelete, error) {
+	result := models.MessageDelete{ID: id}
+
+	msg, err := h.storage.GetChatMessage(boardID, id)
+	if err != nil {
+		return result, storageOpError(err)
+	}
+	if err := h.moderate(boardID, userID, msg.AuthorID, "message"); err != nil {
+		return result, err
+	}
+
+	if err := h.storage.DeleteChatMessage(boardID, id); err != nil {
+		return result, storageOpError(err)
+	}
+
+	h.Broadcast(models.WSMessage{Type: "chat_delete", BoardID: boardID, Payload: result})
+	return result, nil
+}
+
+// PostComment оставляет комментарий к объекту доски или ответ в ветку
+func (h *Hub) PostComment(boardID string, userID int, userName string, req models.CommentRequest) (models.Comment, error) {
+	text, err := messageText(req.Text)
+	if err != nil {
+		return models.Comment{}, err
+	}
+	if req.ObjectID == "" {
+		return models.Comment{}, opError(ErrCodeInvalidPayload, "object id is required")
+	}
+
+	comment := models.Comment{
+		BoardID:    boardID,
+		ObjectID:   req.ObjectID,
+		ParentID:   req.ParentID,
+		AuthorID:   userID,
+		AuthorName: userName,
+		Text:       text,
+		Mentions:   h.mentions(boardID, userID, text),
+		CreatedAt:  clock.Now(),
+	}
+	if err := h.storage.CreateComment(&comment); err != nil {
+		return comment, storageOpError(err)
+	}
+
+	h.touch(boardID)
+	h.Broadcast(models.WSMessage{Type: "comment_create", BoardID: boardID, Payload: comment})
+	h.notifyMentions(comment.Mentions, nil, models.Notification{
+		BoardID:   boardID,
+		ActorID:   userID,
+		ActorName: userName,
+		ObjectID:  comment.ObjectID,
+		MessageID: comment.ID,
+		Text:      text,
+	})
+	return comment, nil
+}
+
+// EditComment меняет текст комментария. Править можно только свои комментарии.
+func (h *Hub) EditComment(boardID string, userID int, edit models.MessageEdit) (models.Comment, error) {
+	text, err := messageText(edit.Text)
+	if err != nil {
+		return models.Comment{}, err
+	}
+
+	comment, err := h.storage.GetComment(boardID, edit.ID)
+	if err != nil {
+		return comment, storageOpError(err)
+	}
+	if comment.AuthorID != userID {
+		return comment, opError(ErrCodeForbidden, "only author can edit comment")
+	}
+
+	previous := comment.Mentions
+	comment, err = h.storage.UpdateComment(boardID, edit.ID, text, h.mentions(boardID, userID, text), clock.Now())
+	if err != nil {
+		return comment, storageOpError(err)
+	}
+
+	h.Broadcast(models.WSMessage{Type: "comment_edit", BoardID: boardID, Payload: comment})
+	h.notifyMentions(comment.Mentions, previous, models.Notification{
+		BoardID:   boardID,
+		ActorID:   userID,
+		ActorName: comment.AuthorName,
+		ObjectID:  comment.ObjectID,
+		MessageID: comment.ID,
+		Text:      text,
+	})
+	return comment, nil
+}
+
+// DeleteComment удаляет комментарий вместе с ответами, если это корень ветки.
+// Удалить можно свой комментарий, владелец доски - любой.
+func (h *Hub) DeleteComment(boardID string, userID int, id int) (models.MessageDelete, error) {
+	result := models.MessageDelete{ID: id}
+
+	comment, err := h.storage.GetComment(boardID, id)
+	if err != nil {
+		return result, storageOpError(err)
+	}
+	if err := h.moderate(boardID, userID, comment.AuthorID, "comment"); err != nil {
+		return result, err
+	}
+
+	replies, err := h.storage.DeleteComment(boardID, id)
+	if err != nil {
+		return result, storageOpError(err)
+	}
+	result.ObjectID = comment.ObjectID
+	result.Replies = replies
+
+	h.Broadcast(models.WSMessage{Type: "comment_delete", BoardID: boardID, Payload: result})
+	return result, nil
+}
+
+// moderate проверяет, что пользователь может удалить запись автора authorID
+func (h *Hub) moderate(boardID string, userID int, authorID int, what string) error {
+	if authorID == userID {
+		return nil
+	}
+	if role, _ := h.storage.GetBoardRole(boardID, userID); role == models.RoleOwner {
+		return nil
+	}
+	return opError(ErrCodeForbidden, fmt.Sprintf("only author or board owner can delete %s", what))
+}
+
+// messageText проверяет текст сообщения или комментария
+func messageText(text string) (string, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", opError(ErrCodeInvalidPayload, "text is required")
+	}
+	if utf8.RuneCountInString(text) > models.MaxMessageLength {
+		return "", opError(ErrCodeInvalidPayload, fmt.Sprintf("text can not be longer than %d characters", models.MaxMessageLength))
+	}
+	return text, nil
+}
+
+// mentions находит в тексте упоминания участников доски: @email или @Имя без учета регистра.
+// Автор сообщения в упоминания не попадает.
+func (h *Hub) mentions(boardID string, authorID int, text string) []int {
+	ids := []int{}
+	if !strings.Contains(text, "@") {
+		return ids
+	}
+
+	members, err := h.storage.GetBoardMembers(boardID)
+	if err != nil {
+		return ids
+	}
+
+	lower := strings.ToLower(text)
+	for _, member := range members {
+		if member.UserID == authorID {
+			continue
+		}
+		if hasMention(lower, member.Email) || hasMention(lower, member.Name) {
+			ids = append(ids, member.UserID)
+		}
+	}
+	return ids
+}
+
+// hasMention ищет в тексте (в нижнем регистре) @name, за которым не продолжается слово
+func hasMention(text string, name string) bool {
+	if name == "" {
+		return false
+	}
+	needle := "@" + strings.ToLower(name)
+	for offset := 0; ; {
+		i := strings.Index(text[offset:], needle)
+		if i < 0 {
+			return false
+		}
+		end := offset + i + len(needle)
+		next, _ := utf8.DecodeRuneInString(text[end:])
+		if end == len(text) || !(unicode.IsLetter(next) || unicode.IsDigit(next) || next == '_') {
+			return true
+		}
+		offset = end
+	}
+}
+
+// notifyMentions создает уведомления упомянутым пользователям. Кто уже был упомянут
+// в прошлой версии сообщения, повторно не уведомляется.
+func (h *Hub) notifyMentions(mentions []int, previous []int, template models.Notification) {
+	notified := make(map[int]bool, len(previous))
+	for _, id := range previous {
+		notified[id] = true
+	}
+
+	if utf8.RuneCountInString(template.Text) > mentionSnippetLength {
+		template.Text = string([]rune(template.Text)[:mentionSnippetLength]) + "…"
+	}
+	template.Type = models.NotificationMention
+
+	for _, id := range mentions {
+		if notified[id] {
+			continue
+		}
+		notification := template
+		notification.UserID = id
+		h.Notify(notification)
+	}
+}
+
+// pageParams разбирает параметры пагинации before и limit
+func pageParams(r *http.Request) (int, int, error) {
+	query := r.URL.Query()
+
+	before := 0
+	if value := query.Get("before"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return 0, 0, errors.New("before must be a positive message id")
+		}
+		before = n
+	}
+
+	limit := models.DefaultPageSize
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > models.MaxPageSize {
+			return 0, 0, fmt.Errorf("limit must be between 1 and %d", models.MaxPageSize)
+		}
+		limit = n
+	}
+
+	return before, limit, nil
+}
+
+// idParam разбирает числовой ID из пути запроса
+func idParam(r *http.Request, name string) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)[name])
+	return id, err == nil && id > 0
+}
+
+// GetChatMessages возвращает страницу чата доски: последние сообщения до курсора before
+func GetChatMessages(s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		boardID := mux.Vars(r)["board_id"]
+
+		if err := objectAccess(s, boardID, user.ID, false); err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		before, limit, err := pageParams(r)
+		if err != nil {
+			utils.SendError(w, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+
+		messages, hasMore, err := s.GetChatMessages(boardID, before, limit)
+		if err != nil {
+			utils.SendError(w, http.StatusNotFound, "board not found", nil)
+			return
+		}
+
+		page := models.ChatPage{Items: messages, HasMore: hasMore}
+		if hasMore {
+			page.Before = messages[0].ID
+		}
+		utils.SendSuccess(w, http.StatusOK, "success", page)
+	}
+}
+
+// PostChatMessage отправляет сообщение в чат доски
+func PostChatMessage(hub *Hub, s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		boardID := mux.Vars(r)["board_id"]
+
+		if err := objectAccess(s, boardID, user.ID, false); err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		var req models.ChatMessageRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.SendError(w, http.StatusBadRequest, "invalid request body", nil)
+			return
+		}
+
+		msg, err := hub.PostChatMessage(boardID, user.ID, user.Name, req.Text)
+		if err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		utils.SendSuccess(w, http.StatusCreated, "message created", msg)
+	}
+}
+
+// EditChatMessage меняет текст своего сообщения
+func EditChatMessage(hub *Hub, s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		boardID := mux.Vars(r)["board_id"]
+
+		if err := objectAccess(s, boardID, user.ID, false); err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		id, ok := idParam(r, "message_id")
+		if !ok {
+			utils.SendError(w, http.StatusNotFound, "message not found", nil)
+			return
+		}
+
+		var req models.ChatMessageRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.SendError(w, http.StatusBadRequest, "invalid request body", nil)
+			return
+		}
+
+		msg, err := hub.EditChatMessage(boardID, user.ID, models.MessageEdit{ID: id, Text: req.Text})
+		if err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		utils.SendSuccess(w, http.StatusOK, "message updated", msg)
+	}
+}
+
+// DeleteChatMessage удаляет сообщение чата
+func DeleteChatMessage(hub *Hub, s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		boardID := mux.Vars(r)["board_id"]
+
+		if err := objectAccess(s, boardID, user.ID, false); err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		id, ok := idParam(r, "message_id")
+		if !ok {
+			utils.SendError(w, http.StatusNotFound, "message not found", nil)
+			return
+		}
+
+		if _, err := hub.DeleteChatMessage(boardID, user.ID, id); err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		utils.SendSuccess(w, http.StatusOK, "message deleted", nil)
+	}
+}
+
+// GetObjectComments возвращает страницу веток комментариев объекта
+func GetObjectComments(s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		vars := mux.Vars(r)
+		boardID := vars["board_id"]
+
+		if err := objectAccess(s, boardID, user.ID, false); err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		before, limit, err := pageParams(r)
+		if err != nil {
+			utils.SendError(w, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+
+		threads, hasMore, err := s.GetCommentThreads(boardID, vars["object_id"], before, limit)
+		if err != nil {
+			utils.SendError(w, http.StatusNotFound, "board not found", nil)
+			return
+		}
+
+		page := models.ChatPage{Items: threads, HasMore: hasMore}
+		if hasMore {
+			page.Before = threads[0].ID
+		}
+		utils.SendSuccess(w, http.StatusOK, "success", page)
+	}
+}
+
+// PostObjectComment оставляет комментарий к объекту или ответ в ветку
+func PostObjectComment(hub *Hub, s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		vars := mux.Vars(r)
+		boardID := vars["board_id"]
+
+		if err := objectAccess(s, boardID, user.ID, false); err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		var req models.CommentRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.SendError(w, http.StatusBadRequest, "invalid request body", nil)
+			return
+		}
+		req.ObjectID = vars["object_id"]
+
+		comment, err := hub.PostComment(boardID, user.ID, user.Name, req)
+		if err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		utils.SendSuccess(w, http.StatusCreated, "comment created", comment)
+	}
+}
+
+// EditComment меняет текст своего комментария
+func EditComment(hub *Hub, s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		boardID := mux.Vars(r)["board_id"]
+
+		if err := objectAccess(s, boardID, user.ID, false); err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		id, ok := idParam(r, "comment_id")
+		if !ok {
+			utils.SendError(w, http.StatusNotFound, "comment not found", nil)
+			return
+		}
+
+		var req models.ChatMessageRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.SendError(w, http.StatusBadRequest, "invalid request body", nil)
+			return
+		}
+
+		comment, err := hub.EditComment(boardID, user.ID, models.MessageEdit{ID: id, Text: req.Text})
+		if err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		utils.SendSuccess(w, http.StatusOK, "comment updated", comment)
+	}
+}
+
+// DeleteComment удаляет комментарий, корень ветки - вместе с ответами
+func DeleteComment(hub *Hub, s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		boardID := mux.Vars(r)["board_id"]
+
+		if err := objectAccess(s, boardID, user.ID, false); err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		id, ok := idParam(r, "comment_id")
+		if !ok {
+			utils.SendError(w, http.StatusNotFound, "comment not found", nil)
+			return
+		}
+
+		result, err := hub.DeleteComment(boardID, user.ID, id)
+		if err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		utils.SendSuccess(w, http.StatusOK, "comment deleted", result)
+	}
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/alexl/go-fake-api/internal/clock"
+	"github.com/alexl/go-fake-api/internal/models"
+	"github.com/alexl/go-fake-api/internal/utils"
+)
+
+// GetClock возвращает текущее время сервера
+func GetClock() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		utils.SendSuccess(w, http.StatusOK, "success", clockState())
+	}
+}
+
+// SetClock замораживает, переставляет или сдвигает часы сервера
+func SetClock() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req models.ClockRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.SendError(w, http.StatusBadRequest, "invalid request body", nil)
+			return
+		}
+
+		var advance time.Duration
+		if req.Advance != "" {
+			d, err := time.ParseDuration(req.Advance)
+			if err != nil || d < 0 {
+				utils.RespondWithValidationError(w, map[string][]string{
+					"advance": {"advance must be a non-negative duration like 1h30m"},
+				})
+				return
+			}
+			advance = d
+		}
+
+		c := clock.Default()
+		if req.Frozen != nil {
+			if *req.Frozen {
+				c.Freeze()
+			} else {
+				c.Unfreeze()
+			}
+		}
+		if req.Time != nil {
+			c.Set(*req.Time)
+		}
+		if advance > 0 {
+			c.Advance(advance)
+		}
+
+		utils.SendSuccess(w, http.StatusOK, "clock updated", clockState())
+	}
+}
+
+// clockState снимок состояния часов
+func clockState() models.ClockState {
+	c := clock.Default()
+	return models.ClockState{
+		Now:    c.Now(),
+		Frozen: c.Frozen(),
+	}
+}
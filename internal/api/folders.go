@@ -0,0 +1,261 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alexl/go-fake-api/internal/clock"
+	"github.com/alexl/go-fake-api/internal/middleware"
+	"github.com/alexl/go-fake-api/internal/models"
+	"github.com/alexl/go-fake-api/internal/storage"
+	"github.com/alexl/go-fake-api/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+// normalizeTag приводит тег к нижнему регистру без пробелов по краям
+func normalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" {
+		return "", errors.New("tag must not be empty")
+	}
+	if utf8.RuneCountInString(tag) > models.MaxTagLength {
+		return "", fmt.Errorf("tag must be at most %d characters", models.MaxTagLength)
+	}
+	return tag, nil
+}
+
+// folderName проверяет название папки
+func folderName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("name is required")
+	}
+	if utf8.RuneCountInString(name) > models.MaxFolderName {
+		return "", fmt.Errorf("name must be at most %d characters", models.MaxFolderName)
+	}
+	return name, nil
+}
+
+// sendFolderError отвечает на ошибку хранилища при работе с папками
+func sendFolderError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, storage.ErrFolderNotFound):
+		utils.SendError(w, http.StatusNotFound, "folder not found", nil)
+	case errors.Is(err, storage.ErrBoardNotFound):
+		utils.SendError(w, http.StatusNotFound, "board not found", nil)
+	case errors.Is(err, storage.ErrFolderCycle), errors.Is(err, storage.ErrFolderDepth):
+		utils.RespondWithValidationError(w, map[string][]string{"parent_id": {err.Error()}})
+	default:
+		utils.SendError(w, http.StatusInternalServerError, "could not update folders", nil)
+	}
+}
+
+// GetFolders возвращает все папки пользователя; дерево строится по parent_id
+func GetFolders(s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+
+		folders, err := s.GetFolders(user.ID)
+		if err != nil {
+			utils.SendError(w, http.StatusInternalServerError, "could not fetch folders", nil)
+			return
+		}
+
+		utils.SendSuccess(w, http.StatusOK, "success", folders)
+	}
+}
+
+// CreateFolder создает папку, parent_id = 0 - на верхнем уровне
+func CreateFolder(s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+
+		var req models.FolderCreateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.SendError(w, http.StatusBadRequest, "invalid request body", nil)
+			return
+		}
+		name, err := folderName(req.Name)
+		if err != nil {
+			utils.RespondWithValidationError(w, map[string][]string{"name": {err.Error()}})
+			return
+		}
+
+		folder := &models.Folder{
+			UserID:    user.ID,
+			ParentID:  req.ParentID,
+			Name:      name,
+			CreatedAt: clock.Now(),
+		}
+		if err := s.CreateFolder(folder); err != nil {
+			sendFolderError(w, err)
+			return
+		}
+
+		utils.SendSuccess(w, http.StatusCreated, "folder created", folder)
+	}
+}
+
+// UpdateFolder переименовывает папку или переносит ее в другую
+func UpdateFolder(s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		id, err := strconv.Atoi(mux.Vars(r)["folder_id"])
+		if err != nil {
+			utils.SendError(w, http.StatusNotFound, "folder not found", nil)
+			return
+		}
+
+		var req models.FolderUpdateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.SendError(w, http.StatusBadRequest, "invalid request body", nil)
+			return
+		}
+		if req.Name != nil {
+			name, err := folderName(*req.Name)
+			if err != nil {
+				utils.RespondWithValidationError(w, map[string][]string{"name": {err.Error()}})
+				return
+			}
+			req.Name = &name
+		}
+
+		folder, err := s.UpdateFolder(user.ID, id, req.Name, req.ParentID)
+		if err != nil {
+			sendFolderError(w, err)
+			return
+		}
+
+		utils.SendSuccess(w, http.StatusOK, "folder updated", folder)
+	}
+}
+
+// DeleteFolder удаляет папку; вложенные папки и доски переходят в родительскую
+func DeleteFolder(s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		id, err := strconv.Atoi(mux.Vars(r)["folder_id"])
+		if err != nil {
+			utils.SendError(w, http.StatusNotFound, "folder not found", nil)
+			return
+		}
+
+		if err := s.DeleteFolder(user.ID, id); err != nil {
+			sendFolderError(w, err)
+			return
+		}
+
+		utils.SendSuccess(w, http.StatusOK, "folder deleted", nil)
+	}
+}
+
+// SetBoardFolder кладет доску в папку пользователя или убирает из папки (folder_id = 0)
+func SetBoardFolder(s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		boardID := mux.Vars(r)["board_id"]
+
+		hasAccess, _ := s.HasBoardAccess(boardID, user.ID)
+		if !hasAccess {
+			utils.SendError(w, http.StatusNotFound, "board not found", nil)
+			return
+		}
+
+		var req models.BoardFolderRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.SendError(w, http.StatusBadRequest, "invalid request body", nil)
+			return
+		}
+
+		if err := s.SetBoardFolder(user.ID, boardID, req.FolderID); err != nil {
+			sendFolderError(w, err)
+			return
+		}
+
+		utils.SendSuccess(w, http.StatusOK, "board moved", req)
+	}
+}
+
+// SetBoardFavorite добавляет доску в избранное (PUT) или убирает из него (DELETE)
+func SetBoardFavorite(s storage.Storage, favorite bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		boardID := mux.Vars(r)["board_id"]
+
+		hasAccess, _ := s.HasBoardAccess(boardID, user.ID)
+		if !hasAccess {
+			utils.SendError(w, http.StatusNotFound, "board not found", nil)
+			return
+		}
+
+		if err := s.SetBoardFavorite(user.ID, boardID, favorite); err != nil {
+			sendFolderError(w, err)
+			return
+		}
+
+		utils.SendSuccess(w, http.StatusOK, "success", map[string]bool{"favorite": favorite})
+	}
+}
+
+// SetBoardTags заменяет теги доски. Теги общие для всех участников, менять их может только владелец.
+func SetBoardTags(s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		boardID := mux.Vars(r)["board_id"]
+
+		board, err := s.GetBoardByID(boardID)
+		if err != nil {
+			utils.SendError(w, http.StatusNotFound, "board not found", nil)
+			return
+		}
+		if board.OwnerID != user.ID {
+			if hasAccess, _ := s.HasBoardAccess(boardID, user.ID); !hasAccess {
+				utils.SendError(w, http.StatusNotFound, "board not found", nil)
+				return
+			}
+			utils.SendError(w, http.StatusForbidden, "only owner can change tags", nil)
+			return
+		}
+
+		var req models.BoardTagsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.SendError(w, http.StatusBadRequest, "invalid request body", nil)
+			return
+		}
+
+		// Теги нормализуются и очищаются от повторов с сохранением порядка
+		tags := []string{}
+		seen := make(map[string]bool)
+		var tagErrors []string
+		for _, value := range req.Tags {
+			tag, err := normalizeTag(value)
+			if err != nil {
+				tagErrors = append(tagErrors, err.Error())
+				continue
+			}
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+		if len(tags) > models.MaxBoardTags {
+			tagErrors = append(tagErrors, fmt.Sprintf("at most %d tags allowed", models.MaxBoardTags))
+		}
+		if len(tagErrors) > 0 {
+			utils.RespondWithValidationError(w, map[string][]string{"tags": tagErrors})
+			return
+		}
+
+		if err := s.SetBoardTags(boardID, tags); err != nil {
+			sendFolderError(w, err)
+			return
+		}
+
+		utils.SendSuccess(w, http.StatusOK, "tags updated", models.BoardTagsRequest{Tags: tags})
+	}
+}
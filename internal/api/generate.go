@@ -0,0 +1,40 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/alexl/go-fake-api/internal/generator"
+	"github.com/alexl/go-fake-api/internal/storage"
+	"github.com/alexl/go-fake-api/internal/utils"
+)
+
+// Generate наполняет хранилище сгенерированными пользователями и досками
+func Generate(s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		opts := generator.Options{
+			Users:           10,
+			BoardsPerUser:   3,
+			ObjectsPerBoard: 12,
+		}
+		// Пустое тело допустимо: используются значения по умолчанию
+		if err := json.NewDecoder(r.Body).Decode(&opts); err != nil && err != io.EOF {
+			utils.SendError(w, http.StatusBadRequest, "invalid request body", nil)
+			return
+		}
+
+		if validationErrors := opts.Validate(); len(validationErrors) > 0 {
+			utils.RespondWithValidationError(w, validationErrors)
+			return
+		}
+
+		result, err := generator.Generate(s, opts)
+		if err != nil {
+			utils.SendError(w, http.StatusInternalServerError, "could not generate data", nil)
+			return
+		}
+
+		utils.SendSuccess(w, http.StatusCreated, "data generated", result)
+	}
+}
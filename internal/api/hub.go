@@ -0,0 +1,771 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"hash/fnv"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alexl/go-fake-api/internal/broker"
+	"github.com/alexl/go-fake-api/internal/models"
+	"github.com/alexl/go-fake-api/internal/msgpack"
+	"github.com/alexl/go-fake-api/internal/storage"
+	"github.com/gorilla/websocket"
+)
+
+// hubShards число шардов карты досок. Доска попадает в шард по хешу ID,
+// поэтому подключения к разным доскам почти не конкурируют за блокировки.
+const hubShards = 64
+
+// boardQueueSize длина очереди рассылки одной доски. Когда очередь заполнена,
+// отправитель ждет: занятая доска тормозит только своих отправителей.
+const boardQueueSize = 256
+
+// publishQueueSize длина очереди публикаций в сетевой брокер. Публикует одна горутина,
+// чтобы недоступный брокер не задерживал изменения; при переполнении события отбрасываются.
+const publishQueueSize = 1024
+
+// Hub управляет всеми подключениями. Каждая доска с клиентами обслуживается
+// своей горутиной (boardHub), которая запускается с первым клиентом
+// и останавливается, когда доска пустеет.
+type Hub struct {
+	shards  [hubShards]hubShard
+	storage storage.Storage
+	config  HubConfig
+	metrics hubMetrics
+	broker  broker.Broker
+	origin  string              // ID экземпляра в брокере
+	epoch   string              // Префикс ID SSE-событий
+	outbox  chan broker.Message // Очередь публикаций в сетевой брокер, разбирает publishLoop; nil - публикация сразу
+	pumps   sync.WaitGroup      // Активные WritePump и SSE-потоки, ждем их при остановке
+
+	// upgrader с подпротоколами форматов и сжатием из настроек
+	upgrader websocket.Upgrader
+
+	historyMu sync.Mutex
+	history   map[string]*boardHistory // boardID -> последние события для SSE, переживает остановку доски
+}
+
+// hubShard часть карты досок со своей блокировкой
+type hubShard struct {
+	mu     sync.Mutex
+	boards map[string]*boardHub
+}
+
+// boardHub рассылка одной доски. Живет, пока на ней есть клиенты, занятые места
+// зрителей или недоставленные события; очередь events разбирает своя горутина.
+type boardHub struct {
+	hub     *Hub
+	id      string
+	refs    int // Клиенты, места зрителей и события в очереди; под shard.mu
+	events  chan boardEvent
+	done    chan struct{}
+	sub     broker.Subscription
+	history *boardHistory // nil у канала уведомлений
+	inbox   bool          // Канал уведомлений пользователя, а не доска
+
+	mu         sync.Mutex
+	clients    map[*Client]bool
+	spectators int // Занятые места зрителей
+}
+
+// boardEvent событие в очереди доски, JSON уже готов
+type boardEvent struct {
+	msgType string
+	data    []byte
+}
+
+// boardHistory кольцевой буфер последних событий доски
+type boardHistory struct {
+	mu     sync.Mutex
+	seq    uint64 // ID последнего события
+	events []historyEvent
+}
+
+// historyEvent событие доски с порядковым номером
+type historyEvent struct {
+	id   uint64
+	data []byte
+}
+
+func NewHub(s storage.Storage, config HubConfig) *Hub {
+	if config.Broker == nil {
+		config.Broker = broker.NewMemory()
+	}
+
+	h := &Hub{
+		storage: s,
+		config:  config,
+		broker:  config.Broker,
+		origin:  broker.NewOrigin(),
+		history: make(map[string]*boardHistory),
+	}
+	h.epoch = config.EventEpoch
+	if h.epoch == "" {
+		h.epoch = h.origin
+	}
+	for i := range h.shards {
+		h.shards[i].boards = make(map[string]*boardHub)
+	}
+	// Брокер в памяти доставляет события сразу и не ждет сети, очередь нужна только сетевому
+	if _, local := config.Broker.(*broker.Memory); !local {
+		h.outbox = make(chan broker.Message, publishQueueSize)
+		go h.publishLoop()
+	}
+
+	// Если клиент предлагает оба формата, выбирается первый из списка сервера
+	h.upgrader = upgrader
+	h.upgrader.Subprotocols = []string{SubprotocolMsgpack, SubprotocolJSON}
+	h.upgrader.EnableCompression = config.Compression
+	return h
+}
+
+// shard возвращает шард доски
+func (h *Hub) shard(boardID string) *hubShard {
+	hash := fnv.New32a()
+	hash.Write([]byte(boardID))
+	return &h.shards[hash.Sum32()%hubShards]
+}
+
+// historyFor возвращает историю доски, создавая ее при первом обращении.
+// У каналов уведомлений истории нет: пропущенное клиент читает через REST.
+func (h *Hub) historyFor(boardID string) *boardHistory {
+	if isInboxRoom(boardID) {
+		return nil
+	}
+
+	h.historyMu.Lock()
+	defer h.historyMu.Unlock()
+
+	history := h.history[boardID]
+	if history == nil {
+		history = &boardHistory{}
+		h.history[boardID] = history
+	}
+	return history
+}
+
+// acquireLocked берет ссылку на доску, при create запуская ее горутину.
+// Без create возвращает nil, если доска не активна. Вызывающий держит s.mu.
+func (h *Hub) acquireLocked(s *hubShard, boardID string, create bool) *boardHub {
+	b := s.boards[boardID]
+	if b == nil {
+		if !create {
+			return nil
+		}
+		b = h.startBoard(boardID)
+		s.boards[boardID] = b
+	}
+	b.refs++
+	return b
+}
+
+// releaseLocked отпускает ссылку на доску и останавливает ее, если ссылок не осталось.
+// Вызывающий держит s.mu.
+func (h *Hub) releaseLocked(s *hubShard, b *boardHub) {
+	b.refs--
+	if b.refs > 0 {
+		return
+	}
+
+	delete(s.boards, b.id)
+	close(b.done)
+	if b.sub != nil {
+		b.sub.Unsubscribe()
+	}
+}
+
+// release отпускает ссылку на доску
+func (h *Hub) release(b *boardHub) {
+	s := h.shard(b.id)
+	s.mu.Lock()
+	h.releaseLocked(s, b)
+	s.mu.Unlock()
+}
+
+// startBoard запускает горутину доски и подписывается на ее события от других экземпляров
+func (h *Hub) startBoard(boardID string) *boardHub {
+	b := &boardHub{
+		hub:     h,
+		id:      boardID,
+		events:  make(chan boardEvent, boardQueueSize),
+		done:    make(chan struct{}),
+		history: h.historyFor(boardID),
+		inbox:   isInboxRoom(boardID),
+		clients: make(map[*Client]bool),
+	}
+
+	sub, err := h.broker.Subscribe(boardID, func(msg broker.Message) {
+		h.receive(b, msg)
+	})
+	if err != nil {
+		h.metrics.brokerErrors.Add(1)
+		log.Printf("broker: subscribe to %s: %v", boardID, err)
+	} else {
+		b.sub = sub
+	}
+
+	go b.run()
+	return b
+}
+
+// run разбирает очередь доски до ее остановки
+func (b *boardHub) run() {
+	for {
+		select {
+		case event := <-b.events:
+			b.mu.Lock()
+			b.deliverLocked(event.msgType, event.data)
+			b.mu.Unlock()
+			b.hub.release(b)
+
+		case <-b.done:
+			// Доска останавливается только без ссылок, то есть с пустой очередью
+			return
+		}
+	}
+}
+
+// record сохраняет событие и возвращает его номер.
+// Присутствие и служебные сообщения в историю не попадают (id = 0).
+func (hist *boardHistory) record(msgType string, msgBytes []byte, size int) uint64 {
+	if hist == nil || msgType == "presence" || size == 0 {
+		return 0
+	}
+
+	hist.mu.Lock()
+	defer hist.mu.Unlock()
+
+	hist.seq++
+	hist.events = append(hist.events, historyEvent{id: hist.seq, data: msgBytes})
+	if len(hist.events) > size {
+		hist.events = hist.events[len(hist.events)-size:]
+	}
+	return hist.seq
+}
+
+// since возвращает номер последнего события и SSE-события после lastEventID.
+// resync = true, если часть событий уже вытеснена из истории или lastEventID
+// больше последнего номера: такой номер выдан не этой историей.
+func (hist *boardHistory) since(epoch string, lastEventID uint64, resume bool) (seq uint64, backlog [][]byte, resync bool) {
+	hist.mu.Lock()
+	defer hist.mu.Unlock()
+
+	if !resume || lastEventID == hist.seq {
+		return hist.seq, nil, false
+	}
+	if lastEventID > hist.seq {
+		return hist.seq, nil, true
+	}
+	if len(hist.events) == 0 || hist.events[0].id > lastEventID+1 {
+		resync = true
+	}
+	for _, event := range hist.events {
+		if event.id > lastEventID {
+			backlog = append(backlog, sseEvent(epoch, event.id, event.data))
+		}
+	}
+	return hist.seq, backlog, resync
+}
+
+// frames кодировки одного сообщения для разных клиентов. Каждая готовится
+// не больше одного раза, при первом клиенте, которому она нужна.
+type frames struct {
+	id      uint64 // Номер события для SSE, 0 - без номера
+	json    []byte
+	sse     []byte
+	msgpack []byte
+}
+
+// forClient возвращает сообщение в формате клиента: JSON, SSE-событие или MessagePack
+func (f *frames) forClient(c *Client) []byte {
+	switch {
+	case c.Stream:
+		if f.sse == nil {
+			f.sse = sseEvent(c.Hub.epoch, f.id, f.json)
+		}
+		return f.sse
+	case c.Format == FormatMsgpack:
+		if f.msgpack == nil {
+			packed, err := msgpack.FromJSON(f.json)
+			if err != nil {
+				// JSON из json.Marshal всегда перекодируется, сюда попасть не должны
+				log.Printf("msgpack: %v", err)
+				return f.json
+			}
+			f.msgpack = packed
+		}
+		return f.msgpack
+	}
+	return f.json
+}
+
+// frame готовит одиночное сообщение без номера в формате клиента
+func (c *Client) frame(msgBytes []byte) []byte {
+	f := frames{json: msgBytes}
+	return f.forClient(c)
+}
+
+// deliverLocked рассылает готовое сообщение клиентам доски, вызывающий держит b.mu
+func (b *boardHub) deliverLocked(msgType string, msgBytes []byte) {
+	id := b.history.record(msgType, msgBytes, b.hub.config.EventHistorySize)
+	b.hub.metrics.messagesBroadcast.Add(1)
+
+	// Каждый формат кодируется один раз на всех клиентов доски
+	f := frames{id: id, json: msgBytes}
+	for client := range b.clients {
+		// Чат и комментарии видят только участники доски, анонимным зрителям они не уходят
+		if client.Spectator && memberMessageTypes[msgType] {
+			continue
+		}
+		select {
+		case client.Send <- f.forClient(client):
+		default:
+			// Очередь клиента переполнена: он не успевает читать, отключаем
+			b.hub.metrics.droppedSlowConsumers.Add(1)
+			client.closeReason = &models.DisconnectPayload{
+				Code:   websocket.CloseTryAgainLater,
+				Reason: ReasonSlowConsumer,
+			}
+			b.removeLocked(client)
+		}
+	}
+}
+
+// sendPresenceLocked рассылает участникам доски список пользователей и число зрителей.
+// Вызывающий держит b.mu.
+func (b *boardHub) sendPresenceLocked() {
+	if b.inbox || len(b.clients) == 0 {
+		return
+	}
+
+	presence := models.Presence{Users: []models.PresenceUser{}}
+	seen := make(map[int]bool)
+	for client := range b.clients {
+		if client.Spectator {
+			presence.Spectators++
+			continue
+		}
+		if !seen[client.UserID] {
+			seen[client.UserID] = true
+			presence.Users = append(presence.Users, models.PresenceUser{
+				ID:   client.UserID,
+				Name: client.UserName,
+			})
+		}
+	}
+	sort.Slice(presence.Users, func(i, j int) bool {
+		return presence.Users[i].ID < presence.Users[j].ID
+	})
+
+	msgBytes, _ := json.Marshal(models.WSMessage{
+		Type:    "presence",
+		BoardID: b.id,
+		Payload: presence,
+	})
+	b.deliverLocked("presence", msgBytes)
+}
+
+// addLocked подключает клиента к доске, вызывающий держит b.mu
+func (b *boardHub) addLocked(client *Client) {
+	b.clients[client] = true
+	b.hub.metrics.connectionsTotal.Add(1)
+	b.sendPresenceLocked()
+}
+
+// removeLocked отключает клиента от доски, вызывающий держит b.mu
+func (b *boardHub) removeLocked(client *Client) {
+	if !b.clients[client] {
+		return
+	}
+
+	delete(b.clients, client)
+	close(client.Send)
+	if client.Spectator {
+		b.spectators--
+	}
+}
+
+// Broadcast рассылает сообщение клиентам доски и другим экземплярам через брокер.
+// JSON готовится один раз, до любых блокировок.
+func (h *Hub) Broadcast(message models.WSMessage) {
+	msgBytes, _ := json.Marshal(message)
+	h.dispatch(message.BoardID, message.Type, msgBytes)
+	h.publish(message.BoardID, msgBytes)
+}
+
+// dispatch ставит событие в очередь доски
+func (h *Hub) dispatch(boardID, msgType string, msgBytes []byte) {
+	s := h.shard(boardID)
+	s.mu.Lock()
+	b := h.acquireLocked(s, boardID, false)
+	if b == nil {
+		// На доске никого нет: только запоминаем событие для возобновления SSE.
+		// Запись под s.mu, чтобы подключающийся клиент не пропустил событие.
+		h.historyFor(boardID).record(msgType, msgBytes, h.config.EventHistorySize)
+		s.mu.Unlock()
+		h.metrics.messagesBroadcast.Add(1)
+		return
+	}
+	s.mu.Unlock()
+
+	// Ссылка отпускается горутиной доски после доставки
+	b.events <- boardEvent{msgType: msgType, data: msgBytes}
+}
+
+// publish отправляет событие доски остальным экземплярам. Сетевому брокеру событие
+// ставится в очередь без ожидания: если брокер не успевает, событие отбрасывается.
+func (h *Hub) publish(boardID string, msgBytes []byte) {
+	msg := broker.Message{Origin: h.origin, BoardID: boardID, Data: msgBytes}
+	if h.outbox == nil {
+		h.send(msg)
+		return
+	}
+	select {
+	case h.outbox <- msg:
+	default:
+		h.metrics.publishDropped.Add(1)
+		log.Printf("broker: publish queue is full, dropped event of %s", boardID)
+	}
+}
+
+// publishLoop отправляет события из очереди в брокер по одному, сохраняя их порядок
+func (h *Hub) publishLoop() {
+	for msg := range h.outbox {
+		h.send(msg)
+	}
+}
+
+// send публикует событие в брокер
+func (h *Hub) send(msg broker.Message) {
+	if err := h.broker.Publish(msg); err != nil {
+		h.metrics.brokerErrors.Add(1)
+		log.Printf("broker: publish to %s: %v", msg.BoardID, err)
+		return
+	}
+	h.metrics.messagesPublished.Add(1)
+}
+
+// receive доставляет локальным клиентам событие, опубликованное другим экземпляром.
+// Собственные события, вернувшиеся из брокера, отбрасываются по Origin.
+func (h *Hub) receive(b *boardHub, msg broker.Message) {
+	if msg.Origin == h.origin {
+		return
+	}
+
+	var header struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(msg.Data, &header); err != nil {
+		h.metrics.brokerErrors.Add(1)
+		return
+	}
+
+	// Доска могла остановиться, пока брокер доставлял событие
+	s := h.shard(b.id)
+	s.mu.Lock()
+	if s.boards[b.id] != b {
+		s.mu.Unlock()
+		return
+	}
+	b.refs++
+	s.mu.Unlock()
+
+	h.metrics.messagesRelayed.Add(1)
+	b.events <- boardEvent{msgType: header.Type, data: msg.Data}
+}
+
+// attach берет ссылку на доску клиента, запуская ее при необходимости
+func (h *Hub) attach(client *Client) *boardHub {
+	s := h.shard(client.BoardID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b := h.acquireLocked(s, client.BoardID, true)
+	client.board = b
+	return b
+}
+
+// boardOf возвращает доску, к которой подключен клиент, или nil
+func (h *Hub) boardOf(client *Client) *boardHub {
+	s := h.shard(client.BoardID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return client.board
+}
+
+// register подключает клиента к доске
+func (h *Hub) register(client *Client) {
+	b := h.attach(client)
+
+	b.mu.Lock()
+	b.addLocked(client)
+	b.mu.Unlock()
+}
+
+// subscribe подключает SSE-клиента и возвращает номер последнего события доски
+// и пропущенные события после lastEventID (пустой - новое подключение без истории).
+// resync = true, если часть событий уже вытеснена из истории или ID выдан другой
+// репликой либо прошлым запуском, и клиенту нужно перечитать доску.
+func (h *Hub) subscribe(client *Client, lastEventID string) (seq uint64, backlog [][]byte, resync bool) {
+	b := h.attach(client)
+
+	// История читается под b.mu: новое событие либо уже в ней, либо придет клиенту
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id, known := parseSSEEventID(h.epoch, lastEventID)
+	seq, backlog, resync = b.history.since(h.epoch, id, known)
+	if lastEventID != "" && !known {
+		resync = true
+	}
+	b.addLocked(client)
+	return seq, backlog, resync
+}
+
+// unregister отключает клиента и отпускает его доску. Повторный вызов ничего не делает.
+func (h *Hub) unregister(client *Client) {
+	s := h.shard(client.BoardID)
+	s.mu.Lock()
+	b := client.board
+	client.board = nil
+	s.mu.Unlock()
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	b.removeLocked(client)
+	b.sendPresenceLocked()
+	b.mu.Unlock()
+
+	s.mu.Lock()
+	h.releaseLocked(s, b)
+	if client.Spectator {
+		// Место зрителя, занятое в reserveSpectator
+		h.releaseLocked(s, b)
+	}
+	s.mu.Unlock()
+}
+
+// reserveSpectator занимает место зрителя на доске, false - лимит исчерпан
+func (h *Hub) reserveSpectator(boardID string) bool {
+	s := h.shard(boardID)
+	s.mu.Lock()
+	b := h.acquireLocked(s, boardID, true)
+	s.mu.Unlock()
+
+	b.mu.Lock()
+	reserved := h.config.MaxSpectators == 0 || b.spectators < h.config.MaxSpectators
+	if reserved {
+		b.spectators++
+	}
+	b.mu.Unlock()
+
+	if !reserved {
+		h.release(b)
+	}
+	return reserved
+}
+
+// releaseSpectator освобождает место зрителя, если подключение не состоялось
+func (h *Hub) releaseSpectator(boardID string) {
+	s := h.shard(boardID)
+	s.mu.Lock()
+	b := s.boards[boardID]
+	s.mu.Unlock()
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	b.spectators--
+	b.mu.Unlock()
+	h.release(b)
+}
+
+// sendTo отправляет сообщение одному клиенту, если он еще подключен
+func (h *Hub) sendTo(client *Client, message models.WSMessage) {
+	msgBytes, _ := json.Marshal(message)
+
+	b := h.boardOf(client)
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.clients[client] {
+		return
+	}
+	select {
+	case client.Send <- client.frame(msgBytes):
+	default:
+	}
+}
+
+// boards возвращает активные доски всех шардов
+func (h *Hub) boards() []*boardHub {
+	var boards []*boardHub
+	for i := range h.shards {
+		s := &h.shards[i]
+		s.mu.Lock()
+		for _, b := range s.boards {
+			boards = append(boards, b)
+		}
+		s.mu.Unlock()
+	}
+	return boards
+}
+
+// checkClients перепроверяет права подключений, отобранных match (nil - всех),
+// и отключает тех, кто их потерял
+func (h *Hub) checkClients(match func(*Client) bool) {
+	var all []*Client
+	for _, b := range h.boards() {
+		b.mu.Lock()
+		for client := range b.clients {
+			if match == nil || match(client) {
+				all = append(all, client)
+			}
+		}
+		b.mu.Unlock()
+	}
+
+	for _, client := range all {
+		if _, reason := client.authorize(); reason != nil {
+			h.Disconnect(client, reason)
+		}
+	}
+}
+
+// RecheckBoard сразу перепроверяет права подключений доски.
+// Вызывается после отзыва доступа, чтобы не ждать периодической проверки.
+func (h *Hub) RecheckBoard(boardID string) {
+	h.checkClients(func(client *Client) bool { return client.BoardID == boardID })
+}
+
+// RecheckUser сразу перепроверяет все подключения пользователя, включая канал уведомлений
+func (h *Hub) RecheckUser(userID int) {
+	h.checkClients(func(client *Client) bool { return !client.Spectator && client.UserID == userID })
+}
+
+// Disconnect отправляет клиенту причину отключения и закрывает соединение
+func (h *Hub) Disconnect(client *Client, reason *models.DisconnectPayload) {
+	b := h.boardOf(client)
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.clients[client] {
+		return
+	}
+
+	msgBytes, _ := json.Marshal(models.WSMessage{
+		Type:    "disconnect",
+		BoardID: client.messageBoardID(),
+		Payload: reason,
+	})
+	select {
+	case client.Send <- client.frame(msgBytes):
+	default:
+	}
+
+	h.metrics.serverDisconnects.Add(1)
+	client.closeReason = reason
+	b.removeLocked(client)
+	b.sendPresenceLocked()
+}
+
+// Shutdown закрывает все подключения с кодом 1001 и ждет, пока клиенты получат close-фрейм
+func (h *Hub) Shutdown(ctx context.Context) error {
+	for _, b := range h.boards() {
+		b.mu.Lock()
+		for client := range b.clients {
+			client.closeReason = &models.DisconnectPayload{
+				Code:   websocket.CloseGoingAway,
+				Reason: ReasonServerShutdown,
+			}
+			b.removeLocked(client)
+		}
+		b.mu.Unlock()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		h.pumps.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Metrics возвращает снимок счетчиков Hub
+func (h *Hub) Metrics() models.HubMetrics {
+	boards := h.boards()
+	active, spectators, packed, inboxes := 0, 0, 0, 0
+	for _, b := range boards {
+		b.mu.Lock()
+		if b.inbox {
+			inboxes++
+		}
+		for client := range b.clients {
+			active++
+			if client.Spectator {
+				spectators++
+			}
+			if client.Format == FormatMsgpack {
+				packed++
+			}
+		}
+		b.mu.Unlock()
+	}
+
+	return models.HubMetrics{
+		ActiveConnections:    active,
+		ActiveSpectators:     spectators,
+		ActiveBoards:         len(boards) - inboxes,
+		ActiveInboxes:        inboxes,
+		ConnectionsTotal:     h.metrics.connectionsTotal.Load(),
+		MessagesReceived:     h.metrics.messagesReceived.Load(),
+		MessagesBroadcast:    h.metrics.messagesBroadcast.Load(),
+		DroppedSlowConsumers: h.metrics.droppedSlowConsumers.Load(),
+		OversizedMessages:    h.metrics.oversizedMessages.Load(),
+		PongTimeouts:         h.metrics.pongTimeouts.Load(),
+		ServerDisconnects:    h.metrics.serverDisconnects.Load(),
+		ActiveMsgpack:        packed,
+		BytesSentJSON:        h.metrics.bytesSentJSON.Load(),
+		BytesSentMsgpack:     h.metrics.bytesSentMsgpack.Load(),
+		Origin:               h.origin,
+		MessagesPublished:    h.metrics.messagesPublished.Load(),
+		PublishDropped:       h.metrics.publishDropped.Load(),
+		MessagesRelayed:      h.metrics.messagesRelayed.Load(),
+		BrokerErrors:         h.metrics.brokerErrors.Load(),
+	}
+}
+
+// Run периодически перепроверяет права всех подключений.
+// Рассылку ведут горутины досок, поэтому без AuthCheckInterval Run сразу завершается.
+func (h *Hub) Run() {
+	if h.config.AuthCheckInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(h.config.AuthCheckInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		h.checkClients(nil)
+	}
+}
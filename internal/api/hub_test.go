@@ -0,0 +1,73 @@
+package api
+
+import "testing"
+
+// benchConfig настройки Hub как у hub-bench: очередь клиента вмещает всю пачку рассылок
+func benchConfig() HubConfig {
+	config := DefaultHubConfig()
+	config.SendBufferSize = 4096
+	return config
+}
+
+func TestBenchmarkHubDeliversToAllClients(t *testing.T) {
+	for _, format := range []string{FormatJSON, FormatMsgpack} {
+		t.Run(format, func(t *testing.T) {
+			opts := HubBenchOptions{Boards: 3, Clients: 10, Messages: 300, Senders: 4, Format: format}
+			result, err := BenchmarkHub(benchConfig(), opts)
+			if err != nil {
+				t.Fatalf("BenchmarkHub: %v", err)
+			}
+
+			// Доски 0, 1, 2 получают по 100 рассылок, на них 4, 3 и 3 клиента
+			if want := int64(100*4 + 100*3 + 100*3); result.Deliveries != want {
+				t.Errorf("Deliveries = %d, want %d", result.Deliveries, want)
+			}
+			if result.DroppedClients != 0 {
+				t.Errorf("DroppedClients = %d, want 0", result.DroppedClients)
+			}
+			if result.BytesDelivered <= 0 {
+				t.Errorf("BytesDelivered = %d, want positive", result.BytesDelivered)
+			}
+		})
+	}
+}
+
+func TestBenchmarkHubOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts HubBenchOptions
+	}{
+		{"no boards", HubBenchOptions{Boards: 0, Clients: 1, Messages: 1, Senders: 1}},
+		{"board without clients", HubBenchOptions{Boards: 2, Clients: 1, Messages: 1, Senders: 1}},
+		{"no messages", HubBenchOptions{Boards: 1, Clients: 1, Messages: 0, Senders: 1}},
+		{"no senders", HubBenchOptions{Boards: 1, Clients: 1, Messages: 1, Senders: 0}},
+		{"unknown format", HubBenchOptions{Boards: 1, Clients: 1, Messages: 1, Senders: 1, Format: "xml"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := BenchmarkHub(benchConfig(), tt.opts); err == nil {
+				t.Errorf("BenchmarkHub(%+v) succeeded, want error", tt.opts)
+			}
+		})
+	}
+}
+
+// BenchmarkHubBroadcast гоняет тот же путь, что и hub-bench: Broadcast без сети
+// до клиентов досок. Одна итерация - одна рассылка.
+func BenchmarkHubBroadcast(b *testing.B) {
+	for _, format := range []string{FormatJSON, FormatMsgpack} {
+		b.Run(format, func(b *testing.B) {
+			opts := HubBenchOptions{Boards: 20, Clients: 500, Messages: b.N, Senders: 8, Format: format}
+			result, err := BenchmarkHub(benchConfig(), opts)
+			if err != nil {
+				b.Fatal(err)
+			}
+			// Рассылка идет без пауз, и при большом b.N медленных читателей отключают,
+			// как это сделал бы сервер: число отключенных показываем рядом с результатом
+			b.ReportMetric(result.DeliveriesPerSecond, "deliveries/s")
+			b.ReportMetric(float64(result.DroppedClients), "dropped")
+			b.SetBytes(result.BytesDelivered / int64(b.N))
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/alexl/go-fake-api/internal/clock"
+	"github.com/alexl/go-fake-api/internal/idgen"
+	"github.com/alexl/go-fake-api/internal/middleware"
+	"github.com/alexl/go-fake-api/internal/models"
+	"github.com/alexl/go-fake-api/internal/storage"
+	"github.com/alexl/go-fake-api/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+// CreateInvite создает ссылку-приглашение на доску
+func CreateInvite(s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		vars := mux.Vars(r)
+		boardID := vars["board_id"]
+
+		board, err := s.GetBoardByID(boardID)
+		if err != nil {
+			utils.SendError(w, http.StatusNotFound, "board not found", nil)
+			return
+		}
+
+		if board.OwnerID != user.ID {
+			utils.SendError(w, http.StatusForbidden, "only owner can invite to board", nil)
+			return
+		}
+
+		var req models.InviteCreateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.SendError(w, http.StatusBadRequest, "invalid request body", nil)
+			return
+		}
+
+		if req.Role == "" {
+			req.Role = models.RoleEditor
+		}
+		if validationErrors := utils.ValidateInvite(req); len(validationErrors) > 0 {
+			utils.RespondWithValidationError(w, validationErrors)
+			return
+		}
+
+		now := clock.Now()
+		invite := &models.BoardInvite{
+			Token:     idgen.Hash(),
+			BoardID:   boardID,
+			Role:      req.Role,
+			Email:     req.Email,
+			CreatedBy: user.ID,
+			CreatedAt: now,
+			MaxUses:   req.MaxUses,
+		}
+		if req.ExpiresIn != "" {
+			d, _ := time.ParseDuration(req.ExpiresIn)
+			expiresAt := now.Add(d)
+			invite.ExpiresAt = &expiresAt
+		}
+
+		if err := s.CreateInvite(invite); err != nil {
+			utils.SendError(w, http.StatusInternalServerError, "could not create invite", nil)
+			return
+		}
+
+		utils.SendSuccess(w, http.StatusCreated, "invite created", invite)
+	}
+}
+
+// GetBoardInvites возвращает приглашения доски
+func GetBoardInvites(s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		vars := mux.Vars(r)
+		boardID := vars["board_id"]
+
+		board, err := s.GetBoardByID(boardID)
+		if err != nil {
+			utils.SendError(w, http.StatusNotFound, "board not found", nil)
+			return
+		}
+
+		if board.OwnerID != user.ID {
+			utils.SendError(w, http.StatusForbidden, "only owner can view invites", nil)
+			return
+		}
+
+		invites, err := s.GetBoardInvites(boardID)
+		if err != nil {
+			utils.SendError(w, http.StatusInternalServerError, "could not fetch invites", nil)
+			return
+		}
+
+		utils.SendSuccess(w, http.StatusOK, "success", invites)
+	}
+}
+
+// RevokeInvite отзывает приглашение
+func RevokeInvite(s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		vars := mux.Vars(r)
+		boardID := vars["board_id"]
+
+		board, err := s.GetBoardByID(boardID)
+		if err != nil {
+			utils.SendError(w, http.StatusNotFound, "board not found", nil)
+			return
+		}
+
+		if board.OwnerID != user.ID {
+			utils.SendError(w, http.StatusForbidden, "only owner can revoke invites", nil)
+			return
+		}
+
+		if err := s.DeleteInvite(boardID, vars["token"]); err != nil {
+			utils.SendError(w, http.StatusNotFound, "invite not found", nil)
+			return
+		}
+
+		utils.SendSuccess(w, http.StatusOK, "invite revoked", nil)
+	}
+}
+
+// RedeemInvite принимает приглашение и выдает доступ текущему пользователю
+func RedeemInvite(s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		vars := mux.Vars(r)
+
+		invite, err := s.RedeemInvite(vars["token"], user, clock.Now())
+		switch err {
+		case nil:
+		case storage.ErrInviteNotFound:
+			utils.SendError(w, http.StatusNotFound, "invite not found", nil)
+			return
+		case storage.ErrInviteEmail:
+			utils.SendError(w, http.StatusForbidden, "invite is issued for another email", nil)
+			return
+		case storage.ErrInviteExpired, storage.ErrInviteExhausted:
+			utils.SendError(w, http.StatusGone, err.Error(), nil)
+			return
+		default:
+			utils.SendError(w, http.StatusInternalServerError, "could not redeem invite", nil)
+			return
+		}
+
+		board, err := s.GetBoardByID(invite.BoardID)
+		if err != nil {
+			utils.SendError(w, http.StatusNotFound, "board not found", nil)
+			return
+		}
+
+		utils.SendSuccess(w, http.StatusOK, "invite accepted", board)
+	}
+}
@@ -0,0 +1,193 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alexl/go-fake-api/internal/middleware"
+	"github.com/alexl/go-fake-api/internal/models"
+	"github.com/alexl/go-fake-api/internal/storage"
+	"github.com/alexl/go-fake-api/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+// CreateLayer добавляет слой поверх остальных и рассылает layers_update со всеми слоями
+func (h *Hub) CreateLayer(boardID string, req models.LayerCreateRequest) (models.Layer, error) {
+	name, err := layerName(req.Name)
+	if err != nil {
+		return models.Layer{}, err
+	}
+
+	layer := models.Layer{Name: name, Visible: req.Visible == nil || *req.Visible}
+	if err := h.storage.CreateLayer(boardID, &layer); err != nil {
+		return layer, storageOpError(err)
+	}
+
+	h.broadcastLayers(boardID)
+	return layer, nil
+}
+
+// UpdateLayer меняет слой и рассылает layers_update. Закреплять слой
+// и снимать закрепление может только владелец доски.
+func (h *Hub) UpdateLayer(boardID string, userID int, layerID string, req models.LayerUpdateRequest) ([]models.Layer, error) {
+	if req.Name != nil {
+		name, err := layerName(*req.Name)
+		if err != nil {
+			return nil, err
+		}
+		req.Name = &name
+	}
+	if req.Position != nil && *req.Position < 0 {
+		return nil, validationError(map[string][]string{"position": {"position must not be negative"}})
+	}
+	if req.Locked != nil {
+		if err := ownerOnly(h.storage, boardID, userID, "only owner can lock layers"); err != nil {
+			return nil, err
+		}
+	}
+
+	layers, err := h.storage.UpdateLayer(boardID, layerID, req)
+	if err != nil {
+		return nil, storageOpError(err)
+	}
+
+	h.touch(boardID)
+	h.Broadcast(models.WSMessage{Type: "layers_update", BoardID: boardID, Payload: layers})
+	return layers, nil
+}
+
+// DeleteLayer удаляет слой и рассылает layer_delete с оставшимися слоями
+// и объектами, перенесенными вне слоев
+func (h *Hub) DeleteLayer(boardID string, layerID string) (models.LayerDelete, error) {
+	layers, moved, err := h.storage.DeleteLayer(boardID, layerID)
+	if err != nil {
+		return models.LayerDelete{}, storageOpError(err)
+	}
+
+	result := models.LayerDelete{ID: layerID, Layers: layers, Moved: moved}
+	h.touch(boardID)
+	h.Broadcast(models.WSMessage{Type: "layer_delete", BoardID: boardID, Payload: result})
+	return result, nil
+}
+
+// broadcastLayers рассылает текущий список слоев доски
+func (h *Hub) broadcastLayers(boardID string) {
+	layers, err := h.storage.GetLayers(boardID)
+	if err != nil {
+		return
+	}
+	h.touch(boardID)
+	h.Broadcast(models.WSMessage{Type: "layers_update", BoardID: boardID, Payload: layers})
+}
+
+// layerName проверяет название слоя
+func layerName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > models.MaxLayerName {
+		return "", validationError(map[string][]string{
+			"name": {fmt.Sprintf("name is required and must be at most %d characters", models.MaxLayerName)},
+		})
+	}
+	return name, nil
+}
+
+// GetLayers возвращает слои доски снизу вверх
+func GetLayers(s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		boardID := mux.Vars(r)["board_id"]
+
+		if err := objectAccess(s, boardID, user.ID, false); err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		layers, err := s.GetLayers(boardID)
+		if err != nil {
+			sendOpError(w, storageOpError(err))
+			return
+		}
+
+		utils.SendSuccess(w, http.StatusOK, "success", layers)
+	}
+}
+
+// CreateLayer добавляет слой на доску
+func CreateLayer(hub *Hub, s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		boardID := mux.Vars(r)["board_id"]
+
+		if err := objectAccess(s, boardID, user.ID, true); err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		var req models.LayerCreateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.SendError(w, http.StatusBadRequest, "invalid request body", nil)
+			return
+		}
+
+		layer, err := hub.CreateLayer(boardID, req)
+		if err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		utils.SendSuccess(w, http.StatusCreated, "layer created", layer)
+	}
+}
+
+// UpdateLayer переименовывает, скрывает, закрепляет или перемещает слой
+func UpdateLayer(hub *Hub, s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		vars := mux.Vars(r)
+		boardID := vars["board_id"]
+
+		if err := objectAccess(s, boardID, user.ID, true); err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		var req models.LayerUpdateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.SendError(w, http.StatusBadRequest, "invalid request body", nil)
+			return
+		}
+
+		layers, err := hub.UpdateLayer(boardID, user.ID, vars["layer_id"], req)
+		if err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		utils.SendSuccess(w, http.StatusOK, "layer updated", layers)
+	}
+}
+
+// DeleteLayer удаляет слой, его объекты остаются на доске вне слоев
+func DeleteLayer(hub *Hub, s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		vars := mux.Vars(r)
+		boardID := vars["board_id"]
+
+		if err := objectAccess(s, boardID, user.ID, true); err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		result, err := hub.DeleteLayer(boardID, vars["layer_id"])
+		if err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		utils.SendSuccess(w, http.StatusOK, "layer deleted", result)
+	}
+}
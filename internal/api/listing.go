@@ -0,0 +1,160 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexl/go-fake-api/internal/clock"
+	"github.com/alexl/go-fake-api/internal/models"
+	"github.com/alexl/go-fake-api/internal/storage"
+)
+
+// boardSorts допустимые сортировки и направление по умолчанию (true - по убыванию)
+var boardSorts = map[string]bool{
+	models.BoardSortLikes:     true,
+	models.BoardSortCreatedAt: true,
+	models.BoardSortName:      false,
+	models.BoardSortActivity:  true,
+}
+
+// boardQuery разбирает параметры списка досок из строки запроса.
+// defaultSort применяется, если сортировка не задана.
+func boardQuery(r *http.Request, defaultSort string) (models.BoardQuery, map[string][]string) {
+	query := r.URL.Query()
+	errors := make(map[string][]string)
+	q := models.BoardQuery{
+		Ownership: query.Get("ownership"),
+		Name:      strings.TrimSpace(query.Get("name")),
+		Sort:      query.Get("sort"),
+		Limit:     models.DefaultPageSize,
+	}
+
+	if q.Sort == "" {
+		q.Sort = defaultSort
+	}
+	desc, ok := boardSorts[q.Sort]
+	if !ok {
+		errors["sort"] = append(errors["sort"], "sort must be one of likes, created_at, name, activity")
+	}
+	switch query.Get("order") {
+	case "":
+		q.Desc = desc
+	case "asc":
+		q.Desc = false
+	case "desc":
+		q.Desc = true
+	default:
+		errors["order"] = append(errors["order"], "order must be asc or desc")
+	}
+
+	if q.Ownership != "" && q.Ownership != models.OwnershipOwned && q.Ownership != models.OwnershipShared {
+		errors["ownership"] = append(errors["ownership"], "ownership must be owned or shared")
+	}
+
+	if value := query.Get("tag"); value != "" {
+		tag, err := normalizeTag(value)
+		if err != nil {
+			errors["tag"] = append(errors["tag"], err.Error())
+		}
+		q.Tag = tag
+	}
+	if value := query.Get("folder_id"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			errors["folder_id"] = append(errors["folder_id"], "folder_id must be a non-negative integer")
+		}
+		q.FolderID = &n
+	}
+	switch query.Get("favorite") {
+	case "", "false":
+	case "true":
+		q.Favorite = true
+	default:
+		errors["favorite"] = append(errors["favorite"], "favorite must be true or false")
+	}
+
+	if value := query.Get("min_likes"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			errors["min_likes"] = append(errors["min_likes"], "min_likes must be a non-negative integer")
+		}
+		q.MinLikes = n
+	}
+
+	for _, field := range []string{"created_from", "created_to"} {
+		value := query.Get(field)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			errors[field] = append(errors[field], field+" must be an RFC3339 time")
+			continue
+		}
+		if field == "created_from" {
+			q.CreatedFrom = &t
+		} else {
+			q.CreatedTo = &t
+		}
+	}
+
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > models.MaxPageSize {
+			errors["limit"] = append(errors["limit"], fmt.Sprintf("limit must be between 1 and %d", models.MaxPageSize))
+		}
+		q.Limit = n
+	}
+
+	cursor, offset := query.Get("cursor"), query.Get("offset")
+	if cursor != "" && offset != "" {
+		errors["cursor"] = append(errors["cursor"], "use either cursor or offset")
+	}
+	if offset != "" {
+		n, err := strconv.Atoi(offset)
+		if err != nil || n < 0 {
+			errors["offset"] = append(errors["offset"], "offset must be a non-negative integer")
+		}
+		q.Offset = n
+	}
+	if cursor != "" {
+		after, err := models.DecodeBoardCursor(cursor)
+		if err != nil {
+			errors["cursor"] = append(errors["cursor"], "invalid cursor")
+		} else if after.Sort != q.Sort || after.Desc != q.Desc {
+			// Ключи курсора имеют смысл только в той сортировке, в которой он выдан
+			errors["cursor"] = append(errors["cursor"], "cursor was issued for another sort order")
+		}
+		q.After = &after
+	}
+
+	return q, errors
+}
+
+// listMeta метаданные страницы списка досок
+func listMeta(q models.BoardQuery, list models.BoardList) models.ListMeta {
+	return models.ListMeta{
+		Total:      list.Total,
+		Limit:      q.Limit,
+		Offset:     q.Offset,
+		HasMore:    list.HasMore,
+		NextCursor: list.NextCursor,
+	}
+}
+
+// optionalUser возвращает пользователя по Bearer-токену, если он передан и действителен.
+// Нужен открытым эндпоинтам, которые авторизованному пользователю отвечают подробнее.
+func optionalUser(s storage.Storage, r *http.Request) *models.User {
+	parts := strings.Split(r.Header.Get("Authorization"), " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return nil
+	}
+	user, err := s.GetUserByToken(parts[1])
+	if err != nil || user.TokenExpired(clock.Now()) {
+		return nil
+	}
+	return user
+}
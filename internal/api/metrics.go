@@ -0,0 +1,14 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/alexl/go-fake-api/internal/utils"
+)
+
+// GetMetrics возвращает счетчики WebSocket Hub
+func GetMetrics(hub *Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		utils.SendSuccess(w, http.StatusOK, "success", hub.Metrics())
+	}
+}
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/alexl/go-fake-api/internal/clock"
+	"github.com/alexl/go-fake-api/internal/middleware"
+	"github.com/alexl/go-fake-api/internal/models"
+	"github.com/alexl/go-fake-api/internal/storage"
+	"github.com/alexl/go-fake-api/internal/utils"
+)
+
+// inboxPrefix префикс комнат Hub с каналами уведомлений. ID досок так не начинаются,
+// поэтому каналы и доски делят шарды и брокер, не пересекаясь.
+const inboxPrefix = "user:"
+
+// inboxRoom комната канала уведомлений пользователя
+func inboxRoom(userID int) string {
+	return inboxPrefix + strconv.Itoa(userID)
+}
+
+// isInboxRoom проверяет, что комната - канал уведомлений, а не доска
+func isInboxRoom(room string) bool {
+	return strings.HasPrefix(room, inboxPrefix)
+}
+
+// Notify сохраняет уведомление и сразу отправляет его во все подключения
+// пользователя к каналу уведомлений, в том числе на других экземплярах
+func (h *Hub) Notify(notification models.Notification) {
+	if notification.BoardName == "" {
+		if board, err := h.storage.GetBoardByID(notification.BoardID); err == nil {
+			notification.BoardName = board.Name
+		}
+	}
+	notification.CreatedAt = clock.Now()
+
+	if err := h.storage.CreateNotification(&notification); err != nil {
+		log.Printf("notifications: could not notify user %d: %v", notification.UserID, err)
+		return
+	}
+	unread, _ := h.storage.CountUnreadNotifications(notification.UserID)
+
+	h.pushToUser(notification.UserID, "notification", models.NotificationPush{
+		Notification: notification,
+		Unread:       unread,
+	})
+}
+
+// MarkNotificationsRead отмечает уведомления прочитанными (ids = nil - все, пустой список - ни одного)
+// и сообщает об этом остальным подключениям пользователя
+func (h *Hub) MarkNotificationsRead(userID int, ids []int) (models.NotificationsRead, error) {
+	marked, unread, err := h.storage.MarkNotificationsRead(userID, ids)
+	if err != nil {
+		return models.NotificationsRead{}, opError(ErrCodeInternal, "could not mark notifications as read")
+	}
+
+	result := models.NotificationsRead{IDs: marked, Unread: unread}
+	if len(marked) > 0 {
+		h.pushToUser(userID, "notifications_read", result)
+	}
+	return result, nil
+}
+
+// pushToUser отправляет сообщение в канал уведомлений пользователя
+func (h *Hub) pushToUser(userID int, msgType string, payload interface{}) {
+	msgBytes, _ := json.Marshal(models.WSMessage{Type: msgType, Payload: payload})
+	room := inboxRoom(userID)
+	h.dispatch(room, msgType, msgBytes)
+	h.publish(room, msgBytes)
+}
+
+// handleInbox выполняет сообщение клиента канала уведомлений
+func (c *Client) handleInbox(msg models.WSClientMessage) (interface{}, error) {
+	switch msg.Type {
+	case "notifications_read":
+		var req models.NotificationsReadRequest
+		// Без payload отмечаются все уведомления
+		if len(msg.Payload) > 0 && string(msg.Payload) != "null" {
+			if err := decodePayload(msg.Payload, &req); err != nil {
+				return nil, err
+			}
+		}
+		return c.Hub.MarkNotificationsRead(c.UserID, req.IDs)
+	}
+
+	return nil, opError(ErrCodeUnknownType, "unknown message type: "+msg.Type)
+}
+
+// GetNotifications возвращает уведомления пользователя от новых к старым и число непрочитанных.
+// ?unread=true оставляет только непрочитанные.
+func GetNotifications(s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+
+		before, limit, err := pageParams(r)
+		if err != nil {
+			utils.SendError(w, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+		unreadOnly := r.URL.Query().Get("unread") == "true"
+
+		notifications, hasMore, err := s.GetNotifications(user.ID, unreadOnly, before, limit)
+		if err != nil {
+			utils.SendError(w, http.StatusInternalServerError, "could not load notifications", nil)
+			return
+		}
+		unread, _ := s.CountUnreadNotifications(user.ID)
+
+		page := models.NotificationPage{Items: notifications, Unread: unread, HasMore: hasMore}
+		if hasMore {
+			page.Before = notifications[len(notifications)-1].ID
+		}
+		utils.SendSuccess(w, http.StatusOK, "success", page)
+	}
+}
+
+// MarkNotificationsRead отмечает уведомления прочитанными: переданные ids или все, если поля ids нет.
+// Пустой список ничего не меняет.
+func MarkNotificationsRead(hub *Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+
+		var req models.NotificationsReadRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			utils.SendError(w, http.StatusBadRequest, "invalid request body", nil)
+			return
+		}
+
+		result, err := hub.MarkNotificationsRead(user.ID, req.IDs)
+		if err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		utils.SendSuccess(w, http.StatusOK, "notifications marked as read", result)
+	}
+}
+
+// ServeNotificationsWs подключает пользователя к его каналу уведомлений.
+// Канал не связан с досками: в него приходят только уведомления этого пользователя.
+func ServeNotificationsWs(hub *Hub, s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("token")
+		user, reason := authorizeUser(s, token)
+		if reason != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		conn, err := hub.upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+
+		client := &Client{
+			Hub:      hub,
+			Conn:     conn,
+			Send:     make(chan []byte, hub.config.SendBufferSize),
+			UserID:   user.ID,
+			UserName: user.Name,
+			BoardID:  inboxRoom(user.ID),
+			Token:    token,
+			Format:   subprotocolFormat(conn.Subprotocol()),
+			Inbox:    true,
+		}
+
+		client.start()
+	}
+}
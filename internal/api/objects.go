@@ -0,0 +1,424 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/alexl/go-fake-api/internal/clock"
+	"github.com/alexl/go-fake-api/internal/idgen"
+	"github.com/alexl/go-fake-api/internal/middleware"
+	"github.com/alexl/go-fake-api/internal/models"
+	"github.com/alexl/go-fake-api/internal/storage"
+	"github.com/alexl/go-fake-api/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+// Коды ошибок операций над объектами
+const (
+	ErrCodeInvalidJSON    = "invalid_json"
+	ErrCodeInvalidPayload = "invalid_payload"
+	ErrCodeUnknownType    = "unknown_type"
+	ErrCodeForbidden      = "forbidden"
+	ErrCodeLocked         = "locked"
+	ErrCodeNotFound       = "not_found"
+	ErrCodeExists         = "exists"
+	ErrCodeInternal       = "internal"
+)
+
+// OpError ошибка операции с кодом для клиента
+type OpError struct {
+	Code    string
+	Message string
+	Errors  map[string][]string // Нарушения по полям, только у invalid_payload
+}
+
+func (e *OpError) Error() string {
+	return e.Message
+}
+
+// opError создает ошибку операции
+func opError(code, message string) *OpError {
+	return &OpError{Code: code, Message: message}
+}
+
+// validationError ошибка операции со списком нарушений по полям
+func validationError(errors map[string][]string) *OpError {
+	return &OpError{Code: ErrCodeInvalidPayload, Message: "Validation error", Errors: errors}
+}
+
+// MaxBatchSize максимальное число операций в одном objects_batch
+const MaxBatchSize = 500
+
+// UpdateObject создает или обновляет объект доски и рассылает изменение.
+// Объект, захваченный другим пользователем или закрепленный, менять нельзя;
+// фокус, z, группа и закрепление сохраняются.
+func (h *Hub) UpdateObject(boardID string, userID int, obj models.BoardObject) (models.BoardObject, error) {
+	if obj.ID == "" {
+		return obj, opError(ErrCodeInvalidPayload, "object id is required")
+	}
+	if violations := utils.ValidateObject(obj); len(violations) > 0 {
+		return obj, validationError(violations)
+	}
+	obj = normalizeObject(obj)
+
+	applied, err := h.storage.ApplyObjectsBatch(boardID, userID, []models.BoardObject{obj}, nil)
+	if err != nil {
+		return obj, storageOpError(err)
+	}
+
+	h.broadcastObjectUpdate(boardID, applied)
+	return applied.Updates[0], nil
+}
+
+// CreateObject создает объект и рассылает его как object_update. Без ID сервер выдает
+// свободный ID сам, занятый ID из запроса - ошибка exists.
+func (h *Hub) CreateObject(boardID string, userID int, obj models.BoardObject) (models.BoardObject, error) {
+	generated := obj.ID == ""
+	if generated {
+		obj.ID = idgen.ObjectID()
+	}
+	if violations := utils.ValidateObject(obj); len(violations) > 0 {
+		return obj, validationError(violations)
+	}
+	obj = normalizeObject(obj)
+
+	for {
+		applied, err := h.storage.CreateBoardObject(boardID, userID, obj)
+		if generated && errors.Is(err, storage.ErrObjectExists) {
+			// Выданный ID мог уже занять объект, созданный с ID из запроса
+			obj.ID = idgen.ObjectID()
+			continue
+		}
+		if err != nil {
+			return obj, storageOpError(err)
+		}
+
+		h.broadcastObjectUpdate(boardID, applied)
+		return applied.Updates[0], nil
+	}
+}
+
+// broadcastObjectUpdate рассылает измененный объект и побочные изменения пакета из одного объекта
+func (h *Hub) broadcastObjectUpdate(boardID string, applied models.ObjectsBatch) {
+	h.touch(boardID)
+	h.Broadcast(models.WSMessage{Type: "object_update", BoardID: boardID, Payload: applied.Updates[0]})
+	// Перемещение группы сдвигает ее объекты, изменение объекта перестраивает его соединители
+	h.broadcastSideEffects(boardID, models.ObjectsBatch{Updates: applied.Updates[1:], Deletes: applied.Deletes})
+}
+
+// FocusObject захватывает объект пользователем
+func (h *Hub) FocusObject(boardID string, userID int, userName string, objectID string) (models.BoardObject, error) {
+	obj, _, err := h.storage.SetObjectFocus(boardID, objectID, userID, userName, true, clock.Now())
+	if err != nil {
+		return obj, storageOpError(err)
+	}
+
+	h.Broadcast(models.WSMessage{Type: "object_focus", BoardID: boardID, Payload: obj})
+	return obj, nil
+}
+
+// BlurObject снимает фокус пользователя с объекта
+func (h *Hub) BlurObject(boardID string, userID int, objectID string) (models.BoardObject, error) {
+	obj, changed, err := h.storage.SetObjectFocus(boardID, objectID, userID, "", false, clock.Now())
+	if err != nil {
+		return obj, storageOpError(err)
+	}
+	if !changed {
+		return obj, nil
+	}
+
+	// Рассылаем обновление о снятии фокуса
+	h.Broadcast(models.WSMessage{Type: "object_blur", BoardID: boardID, Payload: obj})
+	return obj, nil
+}
+
+// DeleteObject удаляет объект, если он не захвачен другим пользователем и не закреплен
+func (h *Hub) DeleteObject(boardID string, userID int, objectID string) error {
+	applied, err := h.storage.ApplyObjectsBatch(boardID, userID, nil, []string{objectID})
+	if err != nil {
+		return storageOpError(err)
+	}
+
+	h.touch(boardID)
+	h.Broadcast(models.WSMessage{Type: "object_delete", BoardID: boardID, Payload: objectID})
+	// Удаление группы удаляет ее объекты, удаление объекта - его соединители
+	// и меняет состав его группы
+	h.broadcastSideEffects(boardID, models.ObjectsBatch{Updates: applied.Updates, Deletes: applied.Deletes[1:]})
+	return nil
+}
+
+// normalizeObject приводит проверенный объект к хранимому виду: путь упрощается
+// до models.MaxPathPoints точек
+func normalizeObject(obj models.BoardObject) models.BoardObject {
+	if obj.Type == models.ObjectPath {
+		obj.Points = utils.SimplifyPath(obj.Points, models.PathTolerance, models.MaxPathPoints)
+	}
+	return obj
+}
+
+// broadcastSideEffects рассылает побочные изменения операции одним objects_batch
+func (h *Hub) broadcastSideEffects(boardID string, batch models.ObjectsBatch) {
+	if len(batch.Updates)+len(batch.Deletes) == 0 {
+		return
+	}
+	h.Broadcast(models.WSMessage{Type: "objects_batch", BoardID: boardID, Payload: batch})
+}
+
+// ApplyBatch атомарно применяет пакет изменений и рассылает его одним сообщением
+// вместе с побочными изменениями групп. Если хотя бы один объект захвачен другим
+// пользователем или закреплен, пакет отклоняется целиком.
+func (h *Hub) ApplyBatch(boardID string, userID int, batch models.ObjectsBatch) (models.ObjectsBatch, error) {
+	if len(batch.Updates)+len(batch.Deletes) == 0 {
+		return batch, opError(ErrCodeInvalidPayload, "batch is empty")
+	}
+	if len(batch.Updates)+len(batch.Deletes) > MaxBatchSize {
+		return batch, opError(ErrCodeInvalidPayload, fmt.Sprintf("batch can not contain more than %d operations", MaxBatchSize))
+	}
+
+	// Один объект может встречаться в пакете только один раз
+	seen := make(map[string]bool)
+	for _, obj := range batch.Updates {
+		if obj.ID == "" {
+			return batch, opError(ErrCodeInvalidPayload, "object id is required")
+		}
+		if seen[obj.ID] {
+			return batch, opError(ErrCodeInvalidPayload, "duplicate object in batch: "+obj.ID)
+		}
+		seen[obj.ID] = true
+	}
+	for _, objectID := range batch.Deletes {
+		if seen[objectID] {
+			return batch, opError(ErrCodeInvalidPayload, "duplicate object in batch: "+objectID)
+		}
+		seen[objectID] = true
+	}
+
+	// Нарушения всех объектов пакета собираются вместе, ключи указывают на объект
+	violations := make(map[string][]string)
+	for i, obj := range batch.Updates {
+		for field, messages := range utils.ValidateObject(obj) {
+			key := fmt.Sprintf("updates[%d].%s", i, field)
+			violations[key] = append(violations[key], messages...)
+		}
+	}
+	if len(violations) > 0 {
+		return batch, validationError(violations)
+	}
+	for i := range batch.Updates {
+		batch.Updates[i] = normalizeObject(batch.Updates[i])
+	}
+
+	result, err := h.storage.ApplyObjectsBatch(boardID, userID, batch.Updates, batch.Deletes)
+	if err != nil {
+		return batch, storageOpError(err)
+	}
+
+	h.touch(boardID)
+	h.Broadcast(models.WSMessage{Type: "objects_batch", BoardID: boardID, Payload: result})
+	return result, nil
+}
+
+// touch отмечает изменение доски для сортировки списков по активности.
+// Захват и снятие фокуса изменением не считаются.
+func (h *Hub) touch(boardID string) {
+	h.storage.TouchBoard(boardID, clock.Now())
+}
+
+// storageOpError переводит ошибку хранилища в ошибку операции
+func storageOpError(err error) error {
+	switch {
+	case errors.Is(err, storage.ErrObjectLocked),
+		errors.Is(err, storage.ErrObjectPinned),
+		errors.Is(err, storage.ErrLayerLocked):
+		return opError(ErrCodeLocked, err.Error())
+	case errors.Is(err, storage.ErrObjectNotFound),
+		errors.Is(err, storage.ErrMessageNotFound),
+		errors.Is(err, storage.ErrCommentNotFound),
+		errors.Is(err, storage.ErrSnapshotNotFound),
+		errors.Is(err, storage.ErrLayerNotFound):
+		return opError(ErrCodeNotFound, err.Error())
+	case errors.Is(err, storage.ErrCommentParent),
+		errors.Is(err, storage.ErrInvalidGroup),
+		errors.Is(err, storage.ErrInvalidConnector):
+		return opError(ErrCodeInvalidPayload, err.Error())
+	case errors.Is(err, storage.ErrObjectExists):
+		return opError(ErrCodeExists, err.Error())
+	case errors.Is(err, storage.ErrBoardFull):
+		return validationError(map[string][]string{"objects": {err.Error()}})
+	case errors.Is(err, storage.ErrTooManyLayers):
+		return validationError(map[string][]string{"layers": {err.Error()}})
+	case errors.Is(err, storage.ErrUnknownLayer):
+		return validationError(map[string][]string{"layer_id": {err.Error()}})
+	}
+	return opError(ErrCodeNotFound, "board not found")
+}
+
+// sendOpError отвечает на REST-запрос ошибкой операции с подходящим HTTP-статусом
+func sendOpError(w http.ResponseWriter, err error) {
+	opErr, ok := err.(*OpError)
+	if !ok {
+		utils.SendError(w, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	if opErr.Errors != nil {
+		utils.RespondWithValidationError(w, opErr.Errors)
+		return
+	}
+
+	status := http.StatusInternalServerError
+	switch opErr.Code {
+	case ErrCodeInvalidJSON:
+		status = http.StatusBadRequest
+	case ErrCodeInvalidPayload:
+		status = http.StatusUnprocessableEntity
+	case ErrCodeForbidden:
+		status = http.StatusForbidden
+	case ErrCodeLocked, ErrCodeExists:
+		status = http.StatusConflict
+	case ErrCodeNotFound:
+		status = http.StatusNotFound
+	}
+	utils.SendError(w, status, opErr.Message, nil)
+}
+
+// objectAccess проверяет доступ пользователя к доске для REST-операций над объектами.
+// Для изменений требуется роль не ниже editor.
+func objectAccess(s storage.Storage, boardID string, userID int, write bool) error {
+	role, _ := s.GetBoardRole(boardID, userID)
+	if role == "" {
+		return opError(ErrCodeNotFound, "board not found")
+	}
+	if write && role == models.RoleViewer {
+		return opError(ErrCodeForbidden, "read-only access")
+	}
+	return nil
+}
+
+// ListBoardObjects возвращает объекты доски
+func ListBoardObjects(s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		boardID := mux.Vars(r)["board_id"]
+
+		if err := objectAccess(s, boardID, user.ID, false); err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		objects, err := s.GetBoardObjects(boardID)
+		if err != nil {
+			utils.SendError(w, http.StatusNotFound, "board not found", nil)
+			return
+		}
+
+		utils.SendSuccess(w, http.StatusOK, "success", objects)
+	}
+}
+
+// GetBoardObject возвращает объект доски
+func GetBoardObject(s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		vars := mux.Vars(r)
+		boardID := vars["board_id"]
+
+		if err := objectAccess(s, boardID, user.ID, false); err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		obj, err := s.GetBoardObject(boardID, vars["object_id"])
+		if err != nil {
+			utils.SendError(w, http.StatusNotFound, "object not found", nil)
+			return
+		}
+
+		utils.SendSuccess(w, http.StatusOK, "success", obj)
+	}
+}
+
+// CreateBoardObject создает объект на доске. Без id сервер выдает его сам.
+func CreateBoardObject(hub *Hub, s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		boardID := mux.Vars(r)["board_id"]
+
+		if err := objectAccess(s, boardID, user.ID, true); err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		var obj models.BoardObject
+		if err := json.NewDecoder(r.Body).Decode(&obj); err != nil {
+			utils.SendError(w, http.StatusBadRequest, "invalid request body", nil)
+			return
+		}
+
+		created, err := hub.CreateObject(boardID, user.ID, obj)
+		if err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		utils.SendSuccess(w, http.StatusCreated, "object created", created)
+	}
+}
+
+// PatchBoardObject частично обновляет объект: поля, которых нет в запросе, не меняются
+func PatchBoardObject(hub *Hub, s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		vars := mux.Vars(r)
+		boardID := vars["board_id"]
+
+		if err := objectAccess(s, boardID, user.ID, true); err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		obj, err := s.GetBoardObject(boardID, vars["object_id"])
+		if err != nil {
+			utils.SendError(w, http.StatusNotFound, "object not found", nil)
+			return
+		}
+
+		// Декодирование поверх текущего объекта меняет только переданные поля
+		if err := json.NewDecoder(r.Body).Decode(&obj); err != nil {
+			utils.SendError(w, http.StatusBadRequest, "invalid request body", nil)
+			return
+		}
+		obj.ID = vars["object_id"]
+
+		updated, err := hub.UpdateObject(boardID, user.ID, obj)
+		if err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		utils.SendSuccess(w, http.StatusOK, "object updated", updated)
+	}
+}
+
+// DeleteBoardObject удаляет объект с доски
+func DeleteBoardObject(hub *Hub, s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		vars := mux.Vars(r)
+		boardID := vars["board_id"]
+
+		if err := objectAccess(s, boardID, user.ID, true); err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		if err := hub.DeleteObject(boardID, user.ID, vars["object_id"]); err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		utils.SendSuccess(w, http.StatusOK, "object deleted", nil)
+	}
+}
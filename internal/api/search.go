@@ -0,0 +1,103 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/alexl/go-fake-api/internal/models"
+	"github.com/alexl/go-fake-api/internal/search"
+	"github.com/alexl/go-fake-api/internal/storage"
+	"github.com/alexl/go-fake-api/internal/utils"
+)
+
+// Search ищет по названиям досок и тексту объектов. Авторизованный пользователь
+// видит доски, к которым у него есть доступ, и публичные, аноним - только публичные.
+// Слова запроса совпадают и по префиксу, чтобы искать по мере ввода.
+func Search(index *search.Index, s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		errors := make(map[string][]string)
+
+		q := strings.TrimSpace(query.Get("q"))
+		if len(search.Tokenize(q)) == 0 {
+			errors["q"] = append(errors["q"], "q must contain at least one word")
+		}
+
+		limit, offset := models.DefaultPageSize, 0
+		if value := query.Get("limit"); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > models.MaxPageSize {
+				errors["limit"] = append(errors["limit"], fmt.Sprintf("limit must be between 1 and %d", models.MaxPageSize))
+			}
+			limit = n
+		}
+		if value := query.Get("offset"); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				errors["offset"] = append(errors["offset"], "offset must be a non-negative integer")
+			}
+			offset = n
+		}
+		if len(errors) > 0 {
+			utils.RespondWithValidationError(w, errors)
+			return
+		}
+
+		user := optionalUser(s, r)
+		hits := index.Search(q, func(boardID string) bool {
+			board, err := s.GetBoardByID(boardID)
+			if err != nil {
+				return false
+			}
+			if board.IsPublic || user == nil {
+				return board.IsPublic
+			}
+			role, err := s.GetBoardRole(boardID, user.ID)
+			return err == nil && role != ""
+		})
+
+		total := len(hits)
+		if offset > total {
+			offset = total
+		}
+		end := offset + limit
+		if end > total {
+			end = total
+		}
+
+		results := make([]models.SearchResult, 0, end-offset)
+		for _, hit := range hits[offset:end] {
+			results = append(results, searchResult(s, hit))
+		}
+
+		utils.SendList(w, http.StatusOK, "success", results, models.ListMeta{
+			Total:   total,
+			Limit:   limit,
+			Offset:  offset,
+			HasMore: end < total,
+		})
+	}
+}
+
+// searchResult дополняет найденный документ названием доски и типом объекта
+func searchResult(s storage.Storage, hit search.Hit) models.SearchResult {
+	result := models.SearchResult{
+		Type:     models.SearchResultBoard,
+		BoardID:  hit.BoardID,
+		ObjectID: hit.ObjectID,
+		Score:    hit.Score,
+		Snippet:  hit.Snippet,
+	}
+	if board, err := s.GetBoardByID(hit.BoardID); err == nil {
+		result.BoardName = board.Name
+	}
+	if hit.ObjectID != "" {
+		result.Type = models.SearchResultObject
+		if obj, err := s.GetBoardObject(hit.BoardID, hit.ObjectID); err == nil {
+			result.ObjectType = obj.Type
+		}
+	}
+	return result
+}
@@ -0,0 +1,323 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alexl/go-fake-api/internal/clock"
+	"github.com/alexl/go-fake-api/internal/middleware"
+	"github.com/alexl/go-fake-api/internal/models"
+	"github.com/alexl/go-fake-api/internal/storage"
+	"github.com/alexl/go-fake-api/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+// RestoreSnapshot заменяет объекты доски объектами снимка и рассылает board_reset:
+// клиенты должны заменить свое состояние доски целиком
+func (h *Hub) RestoreSnapshot(boardID string, userID int, snapshotID int) (models.BoardReset, error) {
+	snapshot, err := h.storage.GetSnapshot(boardID, snapshotID)
+	if err != nil {
+		return models.BoardReset{}, storageOpError(err)
+	}
+
+	restored, err := h.storage.RestoreSnapshot(boardID, snapshotID)
+	if err != nil {
+		return models.BoardReset{}, storageOpError(err)
+	}
+
+	reset := models.BoardReset{
+		SnapshotID: snapshot.ID,
+		Label:      snapshot.Label,
+		RestoredBy: userID,
+		Objects:    restored,
+	}
+
+	h.touch(boardID)
+	h.Broadcast(models.WSMessage{Type: "board_reset", BoardID: boardID, Payload: reset})
+	return reset, nil
+}
+
+// snapshotState объекты снимка по ID или текущие объекты доски (live)
+func snapshotState(s storage.Storage, boardID string, ref string) (map[string]models.BoardObject, error) {
+	if ref == models.SnapshotLive {
+		objects, err := s.GetBoardObjects(boardID)
+		if err != nil {
+			return nil, err
+		}
+		state := make(map[string]models.BoardObject, len(objects))
+		for _, obj := range objects {
+			// Захват - не изменение объекта, в сравнении он не учитывается
+			obj.FocusedBy = nil
+			obj.FocusedAt = nil
+			obj.OwnerName = ""
+			state[obj.ID] = obj
+		}
+		return state, nil
+	}
+
+	id, err := strconv.Atoi(ref)
+	if err != nil {
+		return nil, storage.ErrSnapshotNotFound
+	}
+	snapshot, err := s.GetSnapshot(boardID, id)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.Objects, nil
+}
+
+// diffObjects сравнивает два состояния доски; списки упорядочены по ID объекта
+func diffObjects(from, to map[string]models.BoardObject) (added, removed []models.BoardObject, changed []models.ObjectChange) {
+	added, removed, changed = []models.BoardObject{}, []models.BoardObject{}, []models.ObjectChange{}
+	for id, after := range to {
+		before, ok := from[id]
+		if !ok {
+			added = append(added, after)
+			continue
+		}
+		if fields := changedFields(before, after); len(fields) > 0 {
+			changed = append(changed, models.ObjectChange{ID: id, Fields: fields, Before: before, After: after})
+		}
+	}
+	for id, before := range from {
+		if _, ok := to[id]; !ok {
+			removed = append(removed, before)
+		}
+	}
+
+	sort.Slice(added, func(i, j int) bool { return added[i].ID < added[j].ID })
+	sort.Slice(removed, func(i, j int) bool { return removed[i].ID < removed[j].ID })
+	sort.Slice(changed, func(i, j int) bool { return changed[i].ID < changed[j].ID })
+	return added, removed, changed
+}
+
+// changedFields имена измененных полей объекта
+func changedFields(a, b models.BoardObject) []string {
+	var fields []string
+	if a.Type != b.Type {
+		fields = append(fields, "type")
+	}
+	if a.X != b.X {
+		fields = append(fields, "x")
+	}
+	if a.Y != b.Y {
+		fields = append(fields, "y")
+	}
+	if a.Width != b.Width {
+		fields = append(fields, "width")
+	}
+	if a.Height != b.Height {
+		fields = append(fields, "height")
+	}
+	if a.Rotation != b.Rotation {
+		fields = append(fields, "rotation")
+	}
+	if a.Content != b.Content {
+		fields = append(fields, "content")
+	}
+	if a.Color != b.Color {
+		fields = append(fields, "color")
+	}
+	if a.FontSize != b.FontSize {
+		fields = append(fields, "font_size")
+	}
+	if !equalFloats(a.Points, b.Points) {
+		fields = append(fields, "points")
+	}
+	if a.From != b.From {
+		fields = append(fields, "from")
+	}
+	if a.To != b.To {
+		fields = append(fields, "to")
+	}
+	if a.LayerID != b.LayerID {
+		fields = append(fields, "layer_id")
+	}
+	if a.Z != b.Z {
+		fields = append(fields, "z")
+	}
+	if a.GroupID != b.GroupID {
+		fields = append(fields, "group_id")
+	}
+	if strings.Join(a.Children, "\x00") != strings.Join(b.Children, "\x00") {
+		fields = append(fields, "children")
+	}
+	if a.Locked != b.Locked {
+		fields = append(fields, "locked")
+	}
+	return fields
+}
+
+// equalFloats сравнивает списки чисел поэлементно
+func equalFloats(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// snapshotID ID снимка из пути запроса
+func snapshotID(r *http.Request) (int, error) {
+	return strconv.Atoi(mux.Vars(r)["snapshot_id"])
+}
+
+// CreateSnapshot сохраняет именованный снимок текущих объектов доски
+func CreateSnapshot(s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		boardID := mux.Vars(r)["board_id"]
+
+		if err := objectAccess(s, boardID, user.ID, true); err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		var req models.SnapshotRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.SendError(w, http.StatusBadRequest, "invalid request body", nil)
+			return
+		}
+		label := strings.TrimSpace(req.Label)
+		if label == "" || utf8.RuneCountInString(label) > models.MaxSnapshotLabel {
+			utils.RespondWithValidationError(w, map[string][]string{
+				"label": {"label is required and must be at most 100 characters"},
+			})
+			return
+		}
+
+		snapshot := &models.Snapshot{
+			BoardID:    boardID,
+			Label:      label,
+			AuthorID:   user.ID,
+			AuthorName: user.Name,
+			CreatedAt:  clock.Now(),
+		}
+		if err := s.CreateSnapshot(snapshot); err != nil {
+			sendOpError(w, storageOpError(err))
+			return
+		}
+
+		utils.SendSuccess(w, http.StatusCreated, "snapshot created", snapshot)
+	}
+}
+
+// GetSnapshots возвращает снимки доски от новых к старым, без объектов
+func GetSnapshots(s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		boardID := mux.Vars(r)["board_id"]
+
+		if err := objectAccess(s, boardID, user.ID, false); err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		snapshots, err := s.GetSnapshots(boardID)
+		if err != nil {
+			sendOpError(w, storageOpError(err))
+			return
+		}
+
+		utils.SendSuccess(w, http.StatusOK, "success", snapshots)
+	}
+}
+
+// GetSnapshot возвращает снимок вместе с объектами
+func GetSnapshot(s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		boardID := mux.Vars(r)["board_id"]
+
+		if err := objectAccess(s, boardID, user.ID, false); err != nil {
+			sendOpError(w, err)
+			return
+		}
+		id, err := snapshotID(r)
+		if err != nil {
+			sendOpError(w, storageOpError(storage.ErrSnapshotNotFound))
+			return
+		}
+
+		snapshot, err := s.GetSnapshot(boardID, id)
+		if err != nil {
+			sendOpError(w, storageOpError(err))
+			return
+		}
+
+		utils.SendSuccess(w, http.StatusOK, "success", snapshot)
+	}
+}
+
+// DiffSnapshots сравнивает два снимка или снимок с текущей доской: ?from=<id>&to=<id|live>.
+// to по умолчанию live.
+func DiffSnapshots(s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		boardID := mux.Vars(r)["board_id"]
+
+		if err := objectAccess(s, boardID, user.ID, false); err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		from, to := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+		if from == "" {
+			utils.RespondWithValidationError(w, map[string][]string{"from": {"from is required"}})
+			return
+		}
+		if to == "" {
+			to = models.SnapshotLive
+		}
+
+		fromState, err := snapshotState(s, boardID, from)
+		if err != nil {
+			sendOpError(w, storageOpError(err))
+			return
+		}
+		toState, err := snapshotState(s, boardID, to)
+		if err != nil {
+			sendOpError(w, storageOpError(err))
+			return
+		}
+
+		diff := models.SnapshotDiff{From: from, To: to}
+		diff.Added, diff.Removed, diff.Changed = diffObjects(fromState, toState)
+		utils.SendSuccess(w, http.StatusOK, "success", diff)
+	}
+}
+
+// RestoreSnapshot заменяет объекты доски объектами снимка. Подключенные клиенты
+// получают board_reset с новым набором объектов.
+func RestoreSnapshot(hub *Hub, s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		boardID := mux.Vars(r)["board_id"]
+
+		if err := objectAccess(s, boardID, user.ID, true); err != nil {
+			sendOpError(w, err)
+			return
+		}
+		id, err := snapshotID(r)
+		if err != nil {
+			sendOpError(w, storageOpError(storage.ErrSnapshotNotFound))
+			return
+		}
+
+		reset, err := hub.RestoreSnapshot(boardID, user.ID, id)
+		if err != nil {
+			sendOpError(w, err)
+			return
+		}
+
+		utils.SendSuccess(w, http.StatusOK, "snapshot restored", reset)
+	}
+}
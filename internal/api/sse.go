@@ -0,0 +1,157 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexl/go-fake-api/internal/models"
+	"github.com/alexl/go-fake-api/internal/storage"
+	"github.com/gorilla/mux"
+)
+
+// sseRetry через сколько миллисекунд браузер переподключается после обрыва
+const sseRetry = 3000
+
+// sseEvent форматирует событие Server-Sent Events. События без номера (id = 0)
+// не меняют Last-Event-ID клиента: это присутствие и служебные сообщения.
+func sseEvent(epoch string, id uint64, data []byte) []byte {
+	if id == 0 {
+		return []byte(fmt.Sprintf("data: %s\n\n", data))
+	}
+	return []byte(fmt.Sprintf("id: %s\ndata: %s\n\n", sseEventID(epoch, id), data))
+}
+
+// sseEventID ID события вида <эпоха>-<номер>. Номера событий живут в памяти процесса,
+// поэтому эпоха - ID экземпляра сервера - отличает их от номеров другой реплики или прошлого запуска.
+func sseEventID(epoch string, id uint64) string {
+	return fmt.Sprintf("%s-%d", epoch, id)
+}
+
+// parseSSEEventID номер события из ID. ok = false, если ID выдан другой эпохой или испорчен.
+func parseSSEEventID(epoch string, value string) (uint64, bool) {
+	i := strings.LastIndex(value, "-")
+	if i < 0 || value[:i] != epoch {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(value[i+1:], 10, 64)
+	return id, err == nil
+}
+
+// sseToken достает токен из параметра ?token= или заголовка Authorization.
+// EventSource в браузере не умеет передавать заголовки, поэтому поддерживаем оба способа.
+func sseToken(r *http.Request) string {
+	if token := r.URL.Query().Get("token"); token != "" {
+		return token
+	}
+	parts := strings.Split(r.Header.Get("Authorization"), " ")
+	if len(parts) == 2 && parts[0] == "Bearer" {
+		return parts[1]
+	}
+	return ""
+}
+
+// sseLastEventID ID последнего полученного события: заголовок Last-Event-ID
+// при автоматическом переподключении или параметр ?last_event_id=.
+// Пустая строка, если клиент подключается впервые.
+func sseLastEventID(r *http.Request) string {
+	if value := r.Header.Get("Last-Event-ID"); value != "" {
+		return value
+	}
+	return r.URL.Query().Get("last_event_id")
+}
+
+// ServeEvents отдает события доски потоком Server-Sent Events - только чтение,
+// для клиентов, которым WebSocket недоступен. Поддерживается возобновление по Last-Event-ID.
+func ServeEvents(hub *Hub, s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		boardID := mux.Vars(r)["board_id"]
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+
+		token := sseToken(r)
+		user, role, reason := authorizeToken(s, token, boardID)
+		if reason != nil {
+			if reason.Code == CloseAccessRevoked {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+			} else {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			}
+			return
+		}
+
+		client := &Client{
+			Hub:      hub,
+			Send:     make(chan []byte, hub.config.SendBufferSize),
+			UserID:   user.ID,
+			UserName: user.Name,
+			BoardID:  boardID,
+			Role:     role,
+			Token:    token,
+			Stream:   true,
+		}
+
+		hub.pumps.Add(1)
+		defer hub.pumps.Done()
+		// Отключение идемпотентно: клиент мог быть уже отключен Hub
+		defer hub.unregister(client)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "retry: %d\n\n", sseRetry)
+
+		lastEventID := sseLastEventID(r)
+		seq, backlog, resync := hub.subscribe(client, lastEventID)
+		if lastEventID == "" || resync {
+			// Пустое событие с id задает точку отсчета: после обрыва браузер
+			// пришлет ее в Last-Event-ID и получит все, что пропустил
+			fmt.Fprintf(w, "id: %s\n\n", sseEventID(hub.epoch, seq))
+		}
+		if resync {
+			// Часть событий вытеснена из истории или ID выдан другой репликой либо прошлым
+			// запуском: клиент должен перечитать объекты через REST
+			msgBytes, _ := json.Marshal(models.WSMessage{Type: "resync", BoardID: boardID})
+			w.Write(sseEvent("", 0, msgBytes))
+		}
+		for _, event := range backlog {
+			w.Write(event)
+		}
+		flusher.Flush()
+
+		keepAlive := time.NewTicker(hub.config.KeepAliveInterval)
+		defer keepAlive.Stop()
+
+		for {
+			select {
+			case message, ok := <-client.Send:
+				if !ok {
+					// Hub отключил клиента: событие disconnect уже отправлено в очередь
+					return
+				}
+				if _, err := w.Write(message); err != nil {
+					return
+				}
+				flusher.Flush()
+
+			case <-keepAlive.C:
+				if _, err := w.Write([]byte(": keep-alive\n\n")); err != nil {
+					return
+				}
+				flusher.Flush()
+
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}
+}
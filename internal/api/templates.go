@@ -0,0 +1,210 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/alexl/go-fake-api/internal/clock"
+	"github.com/alexl/go-fake-api/internal/idgen"
+	"github.com/alexl/go-fake-api/internal/middleware"
+	"github.com/alexl/go-fake-api/internal/models"
+	"github.com/alexl/go-fake-api/internal/storage"
+	"github.com/alexl/go-fake-api/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+// copyBoard дополняет новую доску копиями объектов, слоев и тегов исходной и сохраняет ее.
+// Объекты получают новые ID, группы и соединители ссылаются на новые ID, захваты не переносятся. Чат, комментарии и доступы не копируются.
+func copyBoard(s storage.Storage, source *models.Board, board *models.Board) error {
+	objects, err := s.GetBoardObjects(source.ID)
+	if err != nil {
+		return err
+	}
+
+	// Новые ID выдаются заранее, чтобы переписать ссылки групп на объекты
+	ids := make(map[string]string, len(objects))
+	taken := make(map[string]bool, len(objects))
+	for _, obj := range objects {
+		id := idgen.ObjectID()
+		// Генератор по времени может выдать одинаковые ID подряд
+		for taken[id] {
+			id = idgen.ObjectID()
+		}
+		taken[id] = true
+		ids[obj.ID] = id
+	}
+
+	copied := make(map[string]models.BoardObject, len(objects))
+	for _, obj := range objects {
+		obj.ID = ids[obj.ID]
+		if obj.GroupID != "" {
+			obj.GroupID = ids[obj.GroupID]
+		}
+		if obj.Type == models.ObjectConnector {
+			obj.From, obj.To = ids[obj.From], ids[obj.To]
+		}
+		if obj.Children != nil {
+			children := make([]string, len(obj.Children))
+			for i, child := range obj.Children {
+				children[i] = ids[child]
+			}
+			obj.Children = children
+		}
+		obj.FocusedBy = nil
+		obj.FocusedAt = nil
+		obj.OwnerName = ""
+		copied[obj.ID] = obj
+	}
+
+	layers, err := s.GetLayers(source.ID)
+	if err != nil {
+		return err
+	}
+
+	board.Tags = append([]string{}, source.Tags...)
+	board.Layers = layers
+	board.Objects = copied
+	return s.CreateBoard(board)
+}
+
+// templateVisible проверяет, что доска - шаблон, доступный пользователю: публичный
+// шаблон виден всем, приватный - только владельцу и участникам доски
+func templateVisible(s storage.Storage, board *models.Board, userID int) bool {
+	if !board.IsTemplate {
+		return false
+	}
+	if board.IsPublic {
+		return true
+	}
+	hasAccess, _ := s.HasBoardAccess(board.ID, userID)
+	return hasAccess
+}
+
+// DuplicateBoard копирует доску в новую доску вызывающего. Копировать можно
+// любую доступную доску, в том числе только для чтения, и любой публичный шаблон.
+func DuplicateBoard(s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		boardID := mux.Vars(r)["board_id"]
+
+		source, err := s.GetBoardByID(boardID)
+		if err != nil {
+			utils.SendError(w, http.StatusNotFound, "board not found", nil)
+			return
+		}
+		if !templateVisible(s, source, user.ID) {
+			if hasAccess, _ := s.HasBoardAccess(boardID, user.ID); !hasAccess {
+				utils.SendError(w, http.StatusNotFound, "board not found", nil)
+				return
+			}
+		}
+
+		// Тело необязательно
+		var req models.BoardDuplicateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			utils.SendError(w, http.StatusBadRequest, "invalid request body", nil)
+			return
+		}
+		name := strings.TrimSpace(req.Name)
+		if name == "" {
+			name = source.Name + " (copy)"
+		}
+
+		board := &models.Board{
+			ID:        idgen.BoardID(),
+			Hash:      idgen.Hash(),
+			Name:      name,
+			OwnerID:   user.ID,
+			IsPublic:  req.IsPublic,
+			CreatedAt: clock.Now(),
+		}
+		if err := copyBoard(s, source, board); err != nil {
+			utils.SendError(w, http.StatusInternalServerError, "could not duplicate board", nil)
+			return
+		}
+
+		utils.SendSuccess(w, http.StatusCreated, "board duplicated", board)
+	}
+}
+
+// SetBoardTemplate публикует доску в галерее шаблонов или снимает с нее
+func SetBoardTemplate(s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.UserContextKey).(*models.User)
+		boardID := mux.Vars(r)["board_id"]
+
+		board, err := s.GetBoardByID(boardID)
+		if err != nil {
+			utils.SendError(w, http.StatusNotFound, "board not found", nil)
+			return
+		}
+
+		if board.OwnerID != user.ID {
+			utils.SendError(w, http.StatusForbidden, "only owner can publish templates", nil)
+			return
+		}
+
+		var req models.BoardTemplateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.SendError(w, http.StatusBadRequest, "invalid request body", nil)
+			return
+		}
+
+		if err := s.SetBoardTemplate(boardID, req.Enabled); err != nil {
+			utils.SendError(w, http.StatusInternalServerError, "could not update template", nil)
+			return
+		}
+
+		utils.SendSuccess(w, http.StatusOK, "template updated", board)
+	}
+}
+
+// GetTemplates возвращает страницу галереи шаблонов, по умолчанию самые популярные.
+// Публичные шаблоны видны всем, приватные - только участникам доски.
+func GetTemplates(s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q, validationErrors := boardQuery(r, models.BoardSortLikes)
+		if len(validationErrors) > 0 {
+			utils.RespondWithValidationError(w, validationErrors)
+			return
+		}
+		q.Template = true
+
+		user := optionalUser(s, r)
+		if user != nil {
+			q.UserID = user.ID
+		} else if q.Ownership != "" || q.Favorite || q.FolderID != nil {
+			utils.SendError(w, http.StatusUnauthorized, "ownership, favorite and folder filters require authorization", nil)
+			return
+		}
+
+		list, err := s.ListBoards(q)
+		if err != nil {
+			utils.SendError(w, http.StatusInternalServerError, "could not fetch templates", nil)
+			return
+		}
+
+		for i := range list.Boards {
+			hideBoardAccess(s, &list.Boards[i], user)
+		}
+
+		utils.SendList(w, http.StatusOK, "success", list.Boards, listMeta(q, list))
+	}
+}
+
+// hideBoardAccess скрывает от не участника доски настройки доступа: хеш приватной
+// доски с доступом по ссылке открыл бы ее любому, кто видит галерею
+func hideBoardAccess(s storage.Storage, board *models.Board, user *models.User) {
+	if user != nil {
+		if role, _ := s.GetBoardRole(board.ID, user.ID); role != "" {
+			return
+		}
+	}
+	if !board.IsPublic {
+		board.Hash = ""
+	}
+	board.LinkSharing = false
+}
@@ -2,12 +2,17 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
+	"net"
 	"net/http"
-	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/alexl/go-fake-api/internal/broker"
+	"github.com/alexl/go-fake-api/internal/clock"
 	"github.com/alexl/go-fake-api/internal/models"
+	"github.com/alexl/go-fake-api/internal/msgpack"
 	"github.com/alexl/go-fake-api/internal/storage"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
@@ -23,195 +28,517 @@ var upgrader = websocket.Upgrader{
 
 // Client представляет подключенного пользователя
 type Client struct {
-	Hub     *Hub
-	Conn    *websocket.Conn
-	Send    chan []byte
-	UserID  int
+	Hub      *Hub
+	Conn     *websocket.Conn
+	Send     chan []byte
+	UserID   int
 	UserName string
-	BoardID string
+	BoardID  string
+	Role     string
+	// Token токен, с которым открыто соединение; проверяется на каждом изменении
+	Token string
+	// Spectator анонимный зритель публичной доски, только чтение
+	Spectator bool
+	// Hash хеш, по которому подключился зритель
+	Hash string
+	// Stream клиент Server-Sent Events: Conn отсутствует, в Send уходят готовые SSE-события
+	Stream bool
+	// Format формат сообщений, согласованный через Sec-WebSocket-Protocol
+	Format string
+	// Inbox подключение к каналу уведомлений пользователя; BoardID - комната канала, а не доска
+	Inbox bool
+
+	// closeReason причина отключения сервером, выставляется до закрытия Send
+	closeReason *models.DisconnectPayload
+	// board доска, к которой подключен клиент; под блокировкой шарда Hub
+	board *boardHub
 }
 
-// Hub управляет всеми подключениями
-type Hub struct {
-	clients    map[string]map[*Client]bool // boardID -> clients
-	broadcast  chan models.WSMessage
-	register   chan *Client
-	unregister chan *Client
-	storage    storage.Storage
-	mu         sync.Mutex
+// Подпротоколы WebSocket: формат сообщений в обе стороны
+const (
+	SubprotocolJSON    = "board.json"
+	SubprotocolMsgpack = "board.msgpack"
+)
+
+// Форматы сообщений клиента
+const (
+	FormatJSON    = "json"
+	FormatMsgpack = "msgpack"
+)
+
+// Коды закрытия WebSocket при отключении сервером
+const (
+	CloseSessionRevoked = 4001
+	CloseAccessRevoked  = 4003
+)
+
+// Причины отключения клиента сервером
+const (
+	ReasonSessionRevoked   = "session_revoked"
+	ReasonTokenExpired     = "token_expired"
+	ReasonAccessRevoked    = "access_revoked"
+	ReasonBoardUnavailable = "board_unavailable"
+	ReasonSlowConsumer     = "slow_consumer"
+	ReasonServerShutdown   = "server_shutdown"
+)
+
+// Значения по умолчанию для HubConfig
+const (
+	DefaultMaxSpectators     = 50
+	DefaultAuthCheckInterval = 10 * time.Second
+	DefaultWriteWait         = 10 * time.Second
+	DefaultPongWait          = 60 * time.Second
+	DefaultMaxMessageSize    = 64 * 1024
+	DefaultSendBufferSize    = 256
+	DefaultEventHistorySize  = 256
+	DefaultKeepAliveInterval = 15 * time.Second
+)
+
+// HubConfig настройки Hub
+type HubConfig struct {
+	// MaxSpectators лимит анонимных подключений на доску, 0 - без ограничений
+	MaxSpectators int
+	// AuthCheckInterval период перепроверки прав всех подключений, 0 - только при изменениях
+	AuthCheckInterval time.Duration
+	// WriteWait время на запись одного фрейма
+	WriteWait time.Duration
+	// PongWait время ожидания pong (и любого сообщения) от клиента
+	PongWait time.Duration
+	// PingPeriod период отправки ping, должен быть меньше PongWait
+	PingPeriod time.Duration
+	// MaxMessageSize максимальный размер входящего сообщения в байтах
+	MaxMessageSize int64
+	// SendBufferSize длина очереди исходящих сообщений клиента.
+	// Клиент, не успевающий ее разбирать, отключается.
+	SendBufferSize int
+	// EventHistorySize сколько последних событий доски хранится для возобновления SSE по Last-Event-ID
+	EventHistorySize int
+	// EventEpoch префикс ID SSE-событий, пусто - ID экземпляра в брокере (у каждой реплики и запуска свой)
+	EventEpoch string
+	// KeepAliveInterval период keep-alive комментариев в SSE-потоке
+	KeepAliveInterval time.Duration
+	// Broker пересылает события досок между экземплярами сервера, nil - брокер внутри процесса
+	Broker broker.Broker
+	// Compression разрешает сжатие permessage-deflate, если клиент его предлагает
+	Compression bool
 }
 
-func NewHub(s storage.Storage) *Hub {
-	return &Hub{
-		broadcast:  make(chan models.WSMessage),
-		register:   make(chan *Client),
-		unregister: make(chan *Client),
-		clients:    make(map[string]map[*Client]bool),
-		storage:    s,
+// DefaultHubConfig возвращает настройки по умолчанию
+func DefaultHubConfig() HubConfig {
+	return HubConfig{
+		MaxSpectators:     DefaultMaxSpectators,
+		AuthCheckInterval: DefaultAuthCheckInterval,
+		WriteWait:         DefaultWriteWait,
+		PongWait:          DefaultPongWait,
+		PingPeriod:        DefaultPongWait * 9 / 10,
+		MaxMessageSize:    DefaultMaxMessageSize,
+		SendBufferSize:    DefaultSendBufferSize,
+		EventHistorySize:  DefaultEventHistorySize,
+		KeepAliveInterval: DefaultKeepAliveInterval,
 	}
 }
 
-func (h *Hub) Run() {
-	for {
-		select {
-		case client := <-h.register:
-			h.mu.Lock()
-			if h.clients[client.BoardID] == nil {
-				h.clients[client.BoardID] = make(map[*Client]bool)
-			}
-			h.clients[client.BoardID][client] = true
-			h.mu.Unlock()
-
-		case client := <-h.unregister:
-			h.mu.Lock()
-			if clients, ok := h.clients[client.BoardID]; ok {
-				if _, ok := clients[client]; ok {
-					delete(clients, client)
-					close(client.Send)
-					if len(clients) == 0 {
-						delete(h.clients, client.BoardID)
-					}
-				}
-			}
-			h.mu.Unlock()
-
-		case message := <-h.broadcast:
-			h.mu.Lock()
-			msgBytes, _ := json.Marshal(message)
-			for client := range h.clients[message.BoardID] {
-				select {
-				case client.Send <- msgBytes:
-				default:
-					close(client.Send)
-					delete(h.clients[message.BoardID], client)
-				}
-			}
-			h.mu.Unlock()
+// Validate проверяет согласованность настроек
+func (c HubConfig) Validate() error {
+	if c.WriteWait <= 0 || c.PongWait <= 0 || c.PingPeriod <= 0 {
+		return errors.New("websocket timeouts must be positive")
+	}
+	if c.PingPeriod >= c.PongWait {
+		return errors.New("ping period must be less than pong wait")
+	}
+	if c.MaxMessageSize <= 0 || c.SendBufferSize <= 0 {
+		return errors.New("websocket message size and send buffer must be positive")
+	}
+	if c.EventHistorySize < 0 || c.KeepAliveInterval <= 0 {
+		return errors.New("event history size can not be negative and keep-alive interval must be positive")
+	}
+	return nil
+}
+
+// hubMetrics счетчики Hub
+type hubMetrics struct {
+	connectionsTotal     atomic.Int64
+	messagesReceived     atomic.Int64
+	messagesBroadcast    atomic.Int64
+	droppedSlowConsumers atomic.Int64
+	oversizedMessages    atomic.Int64
+	pongTimeouts         atomic.Int64
+	serverDisconnects    atomic.Int64
+	messagesPublished    atomic.Int64
+	publishDropped       atomic.Int64
+	messagesRelayed      atomic.Int64
+	brokerErrors         atomic.Int64
+	bytesSentJSON        atomic.Int64
+	bytesSentMsgpack     atomic.Int64
+}
+
+// authorize перепроверяет токен и доступ клиента к доске.
+// Возвращает актуальную роль или причину отключения, если права потеряны.
+func (c *Client) authorize() (string, *models.DisconnectPayload) {
+	if c.Spectator {
+		board, err := c.Hub.storage.GetBoardByHash(c.Hash)
+		if err != nil || board.ID != c.BoardID || (!board.IsPublic && !board.LinkSharing) {
+			return "", &models.DisconnectPayload{Code: CloseAccessRevoked, Reason: ReasonBoardUnavailable}
+		}
+		return models.RoleViewer, nil
+	}
+	if c.Inbox {
+		user, reason := authorizeUser(c.Hub.storage, c.Token)
+		if reason == nil && user.ID != c.UserID {
+			reason = &models.DisconnectPayload{Code: CloseSessionRevoked, Reason: ReasonSessionRevoked}
 		}
+		return "", reason
 	}
+
+	user, role, reason := authorizeToken(c.Hub.storage, c.Token, c.BoardID)
+	if reason != nil {
+		return "", reason
+	}
+	if user.ID != c.UserID {
+		return "", &models.DisconnectPayload{Code: CloseSessionRevoked, Reason: ReasonSessionRevoked}
+	}
+	return role, nil
+}
+
+// authorizeToken проверяет токен и доступ к доске.
+// Возвращает пользователя и его роль или причину отказа.
+func authorizeToken(s storage.Storage, token string, boardID string) (*models.User, string, *models.DisconnectPayload) {
+	user, reason := authorizeUser(s, token)
+	if reason != nil {
+		return nil, "", reason
+	}
+
+	role, err := s.GetBoardRole(boardID, user.ID)
+	if err != nil || role == "" {
+		return nil, "", &models.DisconnectPayload{Code: CloseAccessRevoked, Reason: ReasonAccessRevoked}
+	}
+	return user, role, nil
+}
+
+// authorizeUser проверяет, что токен действителен, и возвращает его пользователя
+func authorizeUser(s storage.Storage, token string) (*models.User, *models.DisconnectPayload) {
+	user, err := s.GetUserByToken(token)
+	if err != nil {
+		return nil, &models.DisconnectPayload{Code: CloseSessionRevoked, Reason: ReasonSessionRevoked}
+	}
+	if user.TokenExpired(clock.Now()) {
+		return nil, &models.DisconnectPayload{Code: CloseSessionRevoked, Reason: ReasonTokenExpired}
+	}
+	return user, nil
 }
 
 func (c *Client) ReadPump() {
 	defer func() {
-		c.Hub.unregister <- c
+		c.Hub.unregister(c)
 		c.Conn.Close()
 	}()
 
+	config := c.Hub.config
+	c.Conn.SetReadLimit(config.MaxMessageSize)
+	c.Conn.SetReadDeadline(time.Now().Add(config.PongWait))
+	c.Conn.SetPongHandler(func(string) error {
+		c.Conn.SetReadDeadline(time.Now().Add(config.PongWait))
+		return nil
+	})
+
 	for {
-		_, message, err := c.Conn.ReadMessage()
+		messageType, message, err := c.Conn.ReadMessage()
 		if err != nil {
+			var netErr net.Error
+			switch {
+			case errors.Is(err, websocket.ErrReadLimit):
+				// Close-фрейм 1009 библиотека уже отправила
+				c.Hub.metrics.oversizedMessages.Add(1)
+			case errors.As(err, &netErr) && netErr.Timeout():
+				c.Hub.metrics.pongTimeouts.Add(1)
+			case websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseNoStatusReceived):
+				log.Printf("websocket read error: %v", err)
+			}
 			break
 		}
+		// Любое сообщение подтверждает, что клиент жив
+		c.Conn.SetReadDeadline(time.Now().Add(config.PongWait))
+		c.Hub.metrics.messagesReceived.Add(1)
 
-		var wsMsg models.WSMessage
-		if err := json.Unmarshal(message, &wsMsg); err != nil {
+		// Бинарные сообщения - MessagePack, дальше разбираем их так же, как JSON
+		if messageType == websocket.BinaryMessage {
+			if message, err = msgpack.ToJSON(message); err != nil {
+				c.reply("", nil, opError(ErrCodeInvalidJSON, "message is not valid MessagePack"))
+				continue
+			}
+		}
+
+		var msg models.WSClientMessage
+		if err := json.Unmarshal(message, &msg); err != nil {
+			c.reply("", nil, opError(ErrCodeInvalidJSON, "message is not valid JSON"))
 			continue
 		}
 
-		wsMsg.BoardID = c.BoardID // Принудительно ставим BoardID клиента
-
-		// Обработка разных типов сообщений
-		switch wsMsg.Type {
-		case "object_update":
-			var obj models.BoardObject
-			payloadBytes, _ := json.Marshal(wsMsg.Payload)
-			json.Unmarshal(payloadBytes, &obj)
-			
-			// Проверяем фокус
-			board, _ := c.Hub.storage.GetBoardByID(c.BoardID)
-			if existing, ok := board.Objects[obj.ID]; ok {
-				if existing.FocusedBy != nil && *existing.FocusedBy != c.UserID {
-					continue // Нельзя менять чужой фокус
-				}
-			}
-			
-			c.Hub.storage.UpdateBoardObject(c.BoardID, obj)
-			c.Hub.broadcast <- wsMsg
-
-		case "object_focus":
-			objectID := wsMsg.Payload.(string)
-			now := time.Now()
-			obj := models.BoardObject{
-				ID:        objectID,
-				FocusedBy: &c.UserID,
-				FocusedAt: &now,
-				OwnerName: c.UserName,
-			}
-			// Получаем текущий объект, чтобы не затереть данные
-			board, _ := c.Hub.storage.GetBoardByID(c.BoardID)
-			if existing, ok := board.Objects[objectID]; ok {
-				obj.Type = existing.Type
-				obj.X = existing.X
-				obj.Y = existing.Y
-				obj.Width = existing.Width
-				obj.Height = existing.Height
-				obj.Rotation = existing.Rotation
-				obj.Content = existing.Content
-				obj.Color = existing.Color
-			}
-			
-			c.Hub.storage.UpdateBoardObject(c.BoardID, obj)
-			c.Hub.broadcast <- wsMsg
-
-		case "object_blur":
-			objectID := wsMsg.Payload.(string)
-			board, _ := c.Hub.storage.GetBoardByID(c.BoardID)
-			if obj, ok := board.Objects[objectID]; ok {
-				if obj.FocusedBy != nil && *obj.FocusedBy == c.UserID {
-					obj.FocusedBy = nil
-					obj.FocusedAt = nil
-					obj.OwnerName = ""
-					c.Hub.storage.UpdateBoardObject(c.BoardID, obj)
-					
-					// Рассылаем обновление о снятии фокуса
-					wsMsg.Payload = obj
-					c.Hub.broadcast <- wsMsg
-				}
-			}
+		// Права перепроверяются на каждом изменении: токен могли отозвать, доступ - забрать
+		role, reason := c.authorize()
+		if reason != nil {
+			c.Hub.Disconnect(c, reason)
+			continue
+		}
+
+		if c.Inbox {
+			result, err := c.handleInbox(msg)
+			c.reply(msg.RequestID, result, err)
+			continue
+		}
 
-		case "object_delete":
-			objectID := wsMsg.Payload.(string)
-			c.Hub.storage.DeleteBoardObject(c.BoardID, objectID)
-			c.Hub.broadcast <- wsMsg
+		// Зритель не может писать на доску, наблюдатель - только общаться в чате и комментариях
+		if c.Spectator || (role == models.RoleViewer && !memberMessageTypes[msg.Type]) {
+			c.reply(msg.RequestID, nil, opError(ErrCodeForbidden, "read-only access"))
+			continue
 		}
+
+		result, err := c.handle(msg)
+		c.reply(msg.RequestID, result, err)
 	}
 }
 
+// memberMessageTypes сообщения чата и комментариев: их отправляют и получают
+// только участники доски, включая наблюдателей, но не анонимные зрители
+var memberMessageTypes = map[string]bool{
+	"chat_message":   true,
+	"chat_edit":      true,
+	"chat_delete":    true,
+	"comment_create": true,
+	"comment_edit":   true,
+	"comment_delete": true,
+}
+
+// handle выполняет сообщение клиента и возвращает результат для ack
+func (c *Client) handle(msg models.WSClientMessage) (interface{}, error) {
+	switch msg.Type {
+	case "object_update":
+		var obj models.BoardObject
+		if err := decodePayload(msg.Payload, &obj); err != nil {
+			return nil, err
+		}
+		return c.Hub.UpdateObject(c.BoardID, c.UserID, obj)
+
+	case "object_focus":
+		var objectID string
+		if err := decodePayload(msg.Payload, &objectID); err != nil {
+			return nil, err
+		}
+		return c.Hub.FocusObject(c.BoardID, c.UserID, c.UserName, objectID)
+
+	case "object_blur":
+		var objectID string
+		if err := decodePayload(msg.Payload, &objectID); err != nil {
+			return nil, err
+		}
+		return c.Hub.BlurObject(c.BoardID, c.UserID, objectID)
+
+	case "object_delete":
+		var objectID string
+		if err := decodePayload(msg.Payload, &objectID); err != nil {
+			return nil, err
+		}
+		return objectID, c.Hub.DeleteObject(c.BoardID, c.UserID, objectID)
+
+	case "objects_batch":
+		var batch models.ObjectsBatch
+		if err := decodePayload(msg.Payload, &batch); err != nil {
+			return nil, err
+		}
+		return c.Hub.ApplyBatch(c.BoardID, c.UserID, batch)
+
+	case "objects_reorder":
+		var req models.ObjectsReorderRequest
+		if err := decodePayload(msg.Payload, &req); err != nil {
+			return nil, err
+		}
+		return c.Hub.ReorderObjects(c.BoardID, c.UserID, req)
+
+	case "objects_group":
+		var req models.ObjectsGroupRequest
+		if err := decodePayload(msg.Payload, &req); err != nil {
+			return nil, err
+		}
+		return c.Hub.GroupObjects(c.BoardID, c.UserID, req)
+
+	case "objects_ungroup":
+		var groupID string
+		if err := decodePayload(msg.Payload, &groupID); err != nil {
+			return nil, err
+		}
+		return c.Hub.UngroupObjects(c.BoardID, c.UserID, groupID)
+
+	case "object_lock":
+		var req models.ObjectLockRequest
+		if err := decodePayload(msg.Payload, &req); err != nil {
+			return nil, err
+		}
+		return c.Hub.LockObject(c.BoardID, c.UserID, req)
+
+	case "chat_message":
+		var req models.ChatMessageRequest
+		if err := decodePayload(msg.Payload, &req); err != nil {
+			return nil, err
+		}
+		return c.Hub.PostChatMessage(c.BoardID, c.UserID, c.UserName, req.Text)
+
+	case "chat_edit":
+		var edit models.MessageEdit
+		if err := decodePayload(msg.Payload, &edit); err != nil {
+			return nil, err
+		}
+		return c.Hub.EditChatMessage(c.BoardID, c.UserID, edit)
+
+	case "chat_delete":
+		var id int
+		if err := decodePayload(msg.Payload, &id); err != nil {
+			return nil, err
+		}
+		return c.Hub.DeleteChatMessage(c.BoardID, c.UserID, id)
+
+	case "comment_create":
+		var req models.CommentRequest
+		if err := decodePayload(msg.Payload, &req); err != nil {
+			return nil, err
+		}
+		return c.Hub.PostComment(c.BoardID, c.UserID, c.UserName, req)
+
+	case "comment_edit":
+		var edit models.MessageEdit
+		if err := decodePayload(msg.Payload, &edit); err != nil {
+			return nil, err
+		}
+		return c.Hub.EditComment(c.BoardID, c.UserID, edit)
+
+	case "comment_delete":
+		var id int
+		if err := decodePayload(msg.Payload, &id); err != nil {
+			return nil, err
+		}
+		return c.Hub.DeleteComment(c.BoardID, c.UserID, id)
+	}
+
+	return nil, opError(ErrCodeUnknownType, "unknown message type: "+msg.Type)
+}
+
+// messageBoardID board_id в сообщениях клиенту: у канала уведомлений доски нет
+func (c *Client) messageBoardID() string {
+	if c.Inbox {
+		return ""
+	}
+	return c.BoardID
+}
+
+// decodePayload разбирает payload в типизированную структуру
+func decodePayload(raw json.RawMessage, v interface{}) error {
+	if len(raw) == 0 {
+		return opError(ErrCodeInvalidPayload, "payload is required")
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return opError(ErrCodeInvalidPayload, "invalid payload: "+err.Error())
+	}
+	return nil
+}
+
+// reply отправляет клиенту ack или error. Ошибки отправляются всегда,
+// подтверждения - только если клиент передал request_id.
+func (c *Client) reply(requestID string, result interface{}, err error) {
+	if err == nil {
+		if requestID == "" {
+			return
+		}
+		c.Hub.sendTo(c, models.WSMessage{
+			Type:      "ack",
+			BoardID:   c.messageBoardID(),
+			RequestID: requestID,
+			Payload:   result,
+		})
+		return
+	}
+
+	wsErr := models.WSError{Code: ErrCodeInternal, Message: err.Error()}
+	if opErr, ok := err.(*OpError); ok {
+		wsErr.Code = opErr.Code
+		wsErr.Errors = opErr.Errors
+	}
+	c.Hub.sendTo(c, models.WSMessage{
+		Type:      "error",
+		BoardID:   c.messageBoardID(),
+		RequestID: requestID,
+		Payload:   wsErr,
+	})
+}
+
 func (c *Client) WritePump() {
+	config := c.Hub.config
+	ticker := time.NewTicker(config.PingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.Conn.Close()
+		c.Hub.pumps.Done()
+	}()
+
 	for {
 		select {
 		case message, ok := <-c.Send:
+			c.Conn.SetWriteDeadline(time.Now().Add(config.WriteWait))
 			if !ok {
-				c.Conn.WriteMessage(websocket.CloseMessage, []byte{})
+				// Hub закрыл очередь: сообщаем код и причину, если отключил сервер
+				closeMessage := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+				if c.closeReason != nil {
+					closeMessage = websocket.FormatCloseMessage(c.closeReason.Code, c.closeReason.Reason)
+				}
+				c.Conn.WriteMessage(websocket.CloseMessage, closeMessage)
+				return
+			}
+			messageType := websocket.TextMessage
+			if c.Format == FormatMsgpack {
+				messageType = websocket.BinaryMessage
+				c.Hub.metrics.bytesSentMsgpack.Add(int64(len(message)))
+			} else {
+				c.Hub.metrics.bytesSentJSON.Add(int64(len(message)))
+			}
+			if err := c.Conn.WriteMessage(messageType, message); err != nil {
+				return
+			}
+
+		case <-ticker.C:
+			c.Conn.SetWriteDeadline(time.Now().Add(config.WriteWait))
+			if err := c.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
 				return
 			}
-			c.Conn.WriteMessage(websocket.TextMessage, message)
 		}
 	}
 }
 
+// start регистрирует клиента в Hub и запускает его горутины
+func (c *Client) start() {
+	c.Hub.pumps.Add(1)
+	c.Hub.register(c)
+
+	go c.WritePump()
+	go c.ReadPump()
+}
+
 func ServeWs(hub *Hub, s storage.Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
 		boardID := vars["board_id"]
-		
+
 		token := r.URL.Query().Get("token")
 		user, err := s.GetUserByToken(token)
-		if err != nil {
-			// Для публичного просмотра тоже можно разрешить WS, 
-			// но без права редактирования. 
-			// Пока сделаем только для авторизованных.
+		if err != nil || user.TokenExpired(clock.Now()) {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
 
-		hasAccess, _ := s.HasBoardAccess(boardID, user.ID)
-		if !hasAccess {
+		role, _ := s.GetBoardRole(boardID, user.ID)
+		if role == "" {
 			http.Error(w, "Forbidden", http.StatusForbidden)
 			return
 		}
 
-		conn, err := upgrader.Upgrade(w, r, nil)
+		conn, err := hub.upgrader.Upgrade(w, r, nil)
 		if err != nil {
 			log.Println(err)
 			return
@@ -220,15 +547,62 @@ func ServeWs(hub *Hub, s storage.Storage) http.HandlerFunc {
 		client := &Client{
 			Hub:      hub,
 			Conn:     conn,
-			Send:     make(chan []byte, 256),
+			Send:     make(chan []byte, hub.config.SendBufferSize),
 			UserID:   user.ID,
 			UserName: user.Name,
 			BoardID:  boardID,
+			Role:     role,
+			Token:    token,
+			Format:   subprotocolFormat(conn.Subprotocol()),
+		}
+
+		client.start()
+	}
+}
+
+// ServePublicWs подключает анонимного зрителя к публичной доске по хешу.
+// Зритель получает все рассылки доски, но не может ее менять.
+func ServePublicWs(hub *Hub, s storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+
+		board, err := s.GetBoardByHash(vars["hash"])
+		if err != nil || (!board.IsPublic && !board.LinkSharing) {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+
+		if !hub.reserveSpectator(board.ID) {
+			http.Error(w, "Too many spectators", http.StatusServiceUnavailable)
+			return
+		}
+
+		conn, err := hub.upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			hub.releaseSpectator(board.ID)
+			log.Println(err)
+			return
 		}
 
-		client.Hub.register <- client
+		client := &Client{
+			Hub:       hub,
+			Conn:      conn,
+			Send:      make(chan []byte, hub.config.SendBufferSize),
+			BoardID:   board.ID,
+			Role:      models.RoleViewer,
+			Spectator: true,
+			Hash:      board.Hash,
+			Format:    subprotocolFormat(conn.Subprotocol()),
+		}
+
+		client.start()
+	}
+}
 
-		go client.WritePump()
-		go client.ReadPump()
+// subprotocolFormat формат сообщений по согласованному подпротоколу. Без подпротокола - JSON.
+func subprotocolFormat(subprotocol string) string {
+	if subprotocol == SubprotocolMsgpack {
+		return FormatMsgpack
 	}
+	return FormatJSON
 }
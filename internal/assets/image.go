@@ -0,0 +1,128 @@
+package assets
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"github.com/alexl/go-fake-api/internal/models"
+	"golang.org/x/image/draw"
+
+	// Декодеры GIF и WebP регистрируются для image.Decode
+	_ "golang.org/x/image/webp"
+	_ "image/gif"
+)
+
+// Ошибки обработки загруженного файла
+var (
+	ErrUnsupportedType = errors.New("file must be a PNG, JPEG, GIF or WebP image")
+	ErrCorrupt         = errors.New("image can not be decoded")
+	ErrDimensions      = fmt.Errorf("image must be at most %dx%d and %.0f pixels in total",
+		models.MaxAssetDimension, models.MaxAssetDimension, float64(models.MaxAssetPixels))
+)
+
+// supportedTypes MIME-типы, которые принимаются, и расширения файлов для них
+var supportedTypes = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// Image проверенное изображение с уменьшенными вариантами
+type Image struct {
+	MimeType string
+	Width    int
+	Height   int
+	Data     []byte
+	Variants []Variant
+}
+
+// Variant уменьшенная копия изображения
+type Variant struct {
+	Name     string
+	MimeType string
+	Width    int
+	Height   int
+	Data     []byte
+}
+
+// Process определяет тип файла по содержимому, проверяет размеры до полной распаковки
+// и строит уменьшенные варианты. Варианты JPEG сохраняются в JPEG, остальных форматов -
+// в PNG, чтобы не потерять прозрачность; у анимированного GIF берется первый кадр.
+func Process(data []byte) (Image, error) {
+	mimeType := http.DetectContentType(data)
+	if _, ok := supportedTypes[mimeType]; !ok {
+		return Image{}, ErrUnsupportedType
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Image{}, ErrCorrupt
+	}
+	if config.Width > models.MaxAssetDimension || config.Height > models.MaxAssetDimension ||
+		float64(config.Width)*float64(config.Height) > models.MaxAssetPixels {
+		return Image{}, ErrDimensions
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Image{}, ErrCorrupt
+	}
+
+	img := Image{MimeType: mimeType, Width: config.Width, Height: config.Height, Data: data}
+	for _, size := range models.AssetVariantSizes {
+		if config.Width <= size.Size && config.Height <= size.Size {
+			continue
+		}
+		variant, err := resize(src, size.Size, mimeType == "image/jpeg")
+		if err != nil {
+			return Image{}, err
+		}
+		variant.Name = size.Name
+		img.Variants = append(img.Variants, variant)
+	}
+	return img, nil
+}
+
+// Extension расширение файла для MIME-типа
+func Extension(mimeType string) string {
+	if ext, ok := supportedTypes[mimeType]; ok {
+		return ext
+	}
+	return ".png"
+}
+
+// resize вписывает изображение в квадрат size x size с сохранением пропорций
+func resize(src image.Image, size int, asJPEG bool) (Variant, error) {
+	bounds := src.Bounds()
+	width, height := size, size
+	if bounds.Dx() > bounds.Dy() {
+		height = max(1, bounds.Dy()*size/bounds.Dx())
+	} else {
+		width = max(1, bounds.Dx()*size/bounds.Dy())
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Src, nil)
+
+	var buf bytes.Buffer
+	variant := Variant{Width: width, Height: height}
+	if asJPEG {
+		variant.MimeType = "image/jpeg"
+		if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85}); err != nil {
+			return variant, err
+		}
+	} else {
+		variant.MimeType = "image/png"
+		if err := png.Encode(&buf, dst); err != nil {
+			return variant, err
+		}
+	}
+	variant.Data = buf.Bytes()
+	return variant, nil
+}
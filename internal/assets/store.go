@@ -0,0 +1,114 @@
+package assets
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// Файлы-метки каталогов хранилища. Под idPattern они не подходят, поэтому не отдаются.
+const (
+	storeMarker = ".go-fake-api-assets" // Каталог создан хранилищем
+	assetMarker = ".asset"              // Каталог изображения записан хранилищем
+)
+
+// ErrForeignDir каталог создан не хранилищем, удалять из него нельзя
+var ErrForeignDir = errors.New("directory was not created by the assets store")
+
+// Store хранит файлы изображений на диске: <dir>/<asset_id>/<имя файла>
+type Store struct {
+	dir   string
+	owned bool // Каталог помечен хранилищем: Prune может в нем удалять
+}
+
+// NewStore создает хранилище файлов в каталоге, создавая его при необходимости.
+// Новый или пустой каталог помечается как принадлежащий хранилищу.
+func NewStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	marker := filepath.Join(dir, storeMarker)
+	if _, err := os.Stat(marker); err == nil {
+		return &Store{dir: dir, owned: true}, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) > 0 {
+		return &Store{dir: dir}, nil
+	}
+	if err := os.WriteFile(marker, nil, 0o644); err != nil {
+		return nil, err
+	}
+	return &Store{dir: dir, owned: true}, nil
+}
+
+// idPattern допустимые ID изображений и имена файлов: не дают выйти за пределы каталога
+var idPattern = regexp.MustCompile(`^[0-9A-Za-z]+(\.[a-z]+)?$`)
+
+// Save записывает файлы изображения. При ошибке записанные файлы удаляются.
+func (s *Store) Save(id string, files map[string][]byte) error {
+	dir := filepath.Join(s.dir, id)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, assetMarker), nil, 0o644); err != nil {
+		os.RemoveAll(dir)
+		return err
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			os.RemoveAll(dir)
+			return err
+		}
+	}
+	return nil
+}
+
+// Open открывает файл изображения
+func (s *Store) Open(id, name string) (*os.File, error) {
+	if !idPattern.MatchString(id) || !idPattern.MatchString(name) {
+		return nil, os.ErrNotExist
+	}
+	return os.Open(filepath.Join(s.dir, id, name))
+}
+
+// Remove удаляет все файлы изображения
+func (s *Store) Remove(id string) error {
+	if !idPattern.MatchString(id) {
+		return os.ErrNotExist
+	}
+	return os.RemoveAll(filepath.Join(s.dir, id))
+}
+
+// Prune удаляет каталоги изображений, которых нет в known. Описания изображений живут
+// в памяти, поэтому после перезапуска файлы прошлого запуска никто не удалит,
+// а их ID могут снова выпасть. Удаляются только каталоги с меткой хранилища
+// и только в каталоге, который хранилище создало само, иначе ErrForeignDir.
+func (s *Store) Prune(known func(id string) bool) ([]string, error) {
+	if !s.owned {
+		return nil, ErrForeignDir
+	}
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	for _, entry := range entries {
+		id := entry.Name()
+		if !entry.IsDir() || !idPattern.MatchString(id) || known(id) {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(s.dir, id, assetMarker)); err != nil {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(s.dir, id)); err != nil {
+			return removed, err
+		}
+		removed = append(removed, id)
+	}
+	return removed, nil
+}
@@ -0,0 +1,126 @@
+// Package broker рассылает события досок между несколькими экземплярами сервера.
+package broker
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync"
+)
+
+// ErrClosed брокер уже закрыт
+var ErrClosed = errors.New("broker is closed")
+
+// Message событие доски в брокере
+type Message struct {
+	// Origin идентификатор экземпляра, опубликовавшего событие.
+	// По нему Hub отбрасывает собственные события, вернувшиеся из брокера.
+	Origin  string          `json:"origin"`
+	BoardID string          `json:"board_id"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// Handler получает события доски. Вызывается из горутины брокера,
+// не должен надолго блокироваться.
+type Handler func(Message)
+
+// Subscription подписка на события одной доски
+type Subscription interface {
+	Unsubscribe() error
+}
+
+// Broker публикует события досок и доставляет их подписчикам.
+// Subscribe и Unsubscribe не должны ждать сети: Hub вызывает их под своей блокировкой.
+type Broker interface {
+	Publish(msg Message) error
+	Subscribe(boardID string, handler Handler) (Subscription, error)
+	Close() error
+}
+
+// NewOrigin создает случайный идентификатор экземпляра
+func NewOrigin() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Memory брокер внутри процесса. Используется по умолчанию, когда сервер работает
+// в одном экземпляре, и позволяет нескольким Hub в одном процессе видеть события друг друга.
+type Memory struct {
+	mu       sync.RWMutex
+	handlers map[string]map[*memorySubscription]Handler // boardID -> подписчики
+	closed   bool
+}
+
+// memorySubscription подписка на Memory
+type memorySubscription struct {
+	broker  *Memory
+	boardID string
+}
+
+// NewMemory создает брокер внутри процесса
+func NewMemory() *Memory {
+	return &Memory{handlers: make(map[string]map[*memorySubscription]Handler)}
+}
+
+// Publish доставляет событие всем подписчикам доски
+func (m *Memory) Publish(msg Message) error {
+	// Обработчики вызываются без блокировки: подписчик может подписываться
+	// и отписываться, не дожидаясь окончания рассылки
+	m.mu.RLock()
+	if m.closed {
+		m.mu.RUnlock()
+		return ErrClosed
+	}
+	handlers := make([]Handler, 0, len(m.handlers[msg.BoardID]))
+	for _, handler := range m.handlers[msg.BoardID] {
+		handlers = append(handlers, handler)
+	}
+	m.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(msg)
+	}
+	return nil
+}
+
+// Subscribe подписывает обработчик на события доски
+func (m *Memory) Subscribe(boardID string, handler Handler) (Subscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return nil, ErrClosed
+	}
+
+	sub := &memorySubscription{broker: m, boardID: boardID}
+	if m.handlers[boardID] == nil {
+		m.handlers[boardID] = make(map[*memorySubscription]Handler)
+	}
+	m.handlers[boardID][sub] = handler
+	return sub, nil
+}
+
+// Unsubscribe отменяет подписку
+func (s *memorySubscription) Unsubscribe() error {
+	m := s.broker
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.handlers[s.boardID], s)
+	if len(m.handlers[s.boardID]) == 0 {
+		delete(m.handlers, s.boardID)
+	}
+	return nil
+}
+
+// Close отключает всех подписчиков
+func (m *Memory) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.closed = true
+	m.handlers = make(map[string]map[*memorySubscription]Handler)
+	return nil
+}
@@ -0,0 +1,383 @@
+package broker
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Параметры подключения к Redis
+const (
+	DefaultChannelPrefix = "board:"
+	redisDialTimeout     = 5 * time.Second
+	redisIOTimeout       = 5 * time.Second // Запись команды и ожидание ответа
+	redisReconnectDelay  = time.Second
+	redisSubscribeBatch  = 256 // Каналов в одной команде SUBSCRIBE/UNSUBSCRIBE
+)
+
+// Redis брокер поверх pub/sub Redis (или совместимого сервера, например встроенного Server).
+// Каждая доска - отдельный канал prefix+boardID. Публикация и подписка идут
+// по разным соединениям: соединение в режиме подписки не принимает других команд.
+// После обрыва подписки брокер переподключается и подписывается заново.
+// Subscribe и Unsubscribe не пишут в сеть: Hub вызывает их под своей блокировкой,
+// поэтому команды подписки отправляет отдельная горутина (subscribeLoop).
+type Redis struct {
+	addr     string
+	password string
+	prefix   string
+
+	pubMu sync.Mutex
+	pub   *redisConn
+
+	subMu    sync.Mutex
+	sub      *redisConn
+	handlers map[string]map[*redisSubscription]Handler // канал -> подписчики
+	closed   bool
+	done     chan struct{}
+	kick     chan struct{} // Будит subscribeLoop после изменения подписок или соединения
+}
+
+// redisConn соединение с буферизованным чтением и записью
+type redisConn struct {
+	net.Conn
+	r *bufio.Reader
+	w *bufio.Writer
+}
+
+// redisSubscription подписка на Redis
+type redisSubscription struct {
+	broker  *Redis
+	channel string
+}
+
+// NewRedis подключается к серверу по адресу вида redis://[:password@]host[:port].
+// Если сервер недоступен при старте, возвращается ошибка.
+func NewRedis(rawURL string, prefix string) (*Redis, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "redis" || u.Host == "" {
+		return nil, fmt.Errorf("invalid redis url %q", rawURL)
+	}
+
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	password, _ := u.User.Password()
+
+	b := &Redis{
+		addr:     addr,
+		password: password,
+		prefix:   prefix,
+		handlers: make(map[string]map[*redisSubscription]Handler),
+		done:     make(chan struct{}),
+		kick:     make(chan struct{}, 1),
+	}
+
+	sub, err := b.dial()
+	if err != nil {
+		return nil, err
+	}
+	b.sub = sub
+
+	go b.run(sub)
+	go b.subscribeLoop()
+	return b, nil
+}
+
+// dial открывает соединение и проходит AUTH, если задан пароль
+func (b *Redis) dial() (*redisConn, error) {
+	conn, err := net.DialTimeout("tcp", b.addr, redisDialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &redisConn{Conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}
+
+	if b.password != "" {
+		if _, err := c.do([]byte("AUTH"), []byte(b.password)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// do выполняет команду и читает ответ. Зависший сервер прерывается по redisIOTimeout.
+func (c *redisConn) do(args ...[]byte) (interface{}, error) {
+	c.SetDeadline(time.Now().Add(redisIOTimeout))
+	defer c.SetDeadline(time.Time{})
+
+	if err := writeCommand(c.w, args...); err != nil {
+		return nil, err
+	}
+	reply, err := readValue(c.r)
+	if err != nil {
+		return nil, err
+	}
+	if e, ok := reply.(respError); ok {
+		return nil, e
+	}
+	return reply, nil
+}
+
+// Publish публикует событие в канал доски. При обрыве соединение открывается заново.
+func (b *Redis) Publish(msg Message) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	b.pubMu.Lock()
+	defer b.pubMu.Unlock()
+
+	// Вторая попытка нужна, если сервер закрыл простаивающее соединение
+	for attempt := 0; ; attempt++ {
+		if b.pub == nil {
+			if b.pub, err = b.dial(); err != nil {
+				return err
+			}
+		}
+
+		_, err = b.pub.do([]byte("PUBLISH"), []byte(b.prefix+msg.BoardID), payload)
+		if err == nil {
+			return nil
+		}
+		if _, ok := err.(respError); ok || attempt > 0 {
+			return err
+		}
+		b.pub.Close()
+		b.pub = nil
+	}
+}
+
+// Subscribe подписывает обработчик на канал доски
+func (b *Redis) Subscribe(boardID string, handler Handler) (Subscription, error) {
+	b.subMu.Lock()
+	defer b.subMu.Unlock()
+
+	if b.closed {
+		return nil, ErrClosed
+	}
+
+	channel := b.prefix + boardID
+	sub := &redisSubscription{broker: b, channel: channel}
+	if b.handlers[channel] == nil {
+		b.handlers[channel] = make(map[*redisSubscription]Handler)
+		b.wake()
+	}
+	b.handlers[channel][sub] = handler
+	return sub, nil
+}
+
+// Unsubscribe отменяет подписку. Последний подписчик канала отписывает и соединение.
+func (s *redisSubscription) Unsubscribe() error {
+	b := s.broker
+	b.subMu.Lock()
+	defer b.subMu.Unlock()
+
+	handlers, ok := b.handlers[s.channel]
+	if !ok {
+		return nil
+	}
+	delete(handlers, s)
+	if len(handlers) == 0 {
+		delete(b.handlers, s.channel)
+		b.wake()
+	}
+	return nil
+}
+
+// wake будит subscribeLoop, не дожидаясь его
+func (b *Redis) wake() {
+	select {
+	case b.kick <- struct{}{}:
+	default:
+	}
+}
+
+// subscribeLoop приводит подписки соединения к каналам, у которых есть подписчики.
+// В сокет пишет только эта горутина и без subMu, поэтому зависший сервер
+// не задерживает ни Subscribe, ни доставку событий.
+func (b *Redis) subscribeLoop() {
+	var conn *redisConn
+	subscribed := make(map[string]bool) // Каналы, на которые подписано conn
+	for {
+		select {
+		case <-b.done:
+			return
+		case <-b.kick:
+		}
+
+		b.subMu.Lock()
+		if b.sub != conn {
+			conn = b.sub
+			subscribed = make(map[string]bool)
+		}
+		var add, remove []string
+		for channel := range b.handlers {
+			if !subscribed[channel] {
+				add = append(add, channel)
+			}
+		}
+		for channel := range subscribed {
+			if _, ok := b.handlers[channel]; !ok {
+				remove = append(remove, channel)
+			}
+		}
+		b.subMu.Unlock()
+		if conn == nil {
+			// Соединения нет: resubscribe разбудит цикл после переподключения
+			continue
+		}
+
+		if err := conn.subscribe("SUBSCRIBE", add); err != nil {
+			// Обрыв увидит цикл чтения: он переподключится, и сверка пройдет заново
+			conn.Close()
+			continue
+		}
+		for _, channel := range add {
+			subscribed[channel] = true
+		}
+		if err := conn.subscribe("UNSUBSCRIBE", remove); err != nil {
+			conn.Close()
+			continue
+		}
+		for _, channel := range remove {
+			delete(subscribed, channel)
+		}
+	}
+}
+
+// subscribe отправляет команду подписки пачками каналов. Ответы читает цикл чтения.
+func (c *redisConn) subscribe(command string, channels []string) error {
+	for len(channels) > 0 {
+		n := len(channels)
+		if n > redisSubscribeBatch {
+			n = redisSubscribeBatch
+		}
+		args := make([][]byte, 0, n+1)
+		args = append(args, []byte(command))
+		for _, channel := range channels[:n] {
+			args = append(args, []byte(channel))
+		}
+		c.SetWriteDeadline(time.Now().Add(redisIOTimeout))
+		if err := writeCommand(c.w, args...); err != nil {
+			return err
+		}
+		channels = channels[n:]
+	}
+	return nil
+}
+
+// run читает события из соединения подписки и переподключается после обрыва
+func (b *Redis) run(conn *redisConn) {
+	for {
+		err := b.read(conn)
+
+		b.subMu.Lock()
+		b.sub = nil
+		closed := b.closed
+		b.subMu.Unlock()
+		conn.Close()
+		if closed {
+			return
+		}
+		log.Printf("broker: subscription to %s lost: %v", b.addr, err)
+
+		for conn = nil; conn == nil; {
+			select {
+			case <-b.done:
+				return
+			case <-time.After(redisReconnectDelay):
+			}
+			if conn, err = b.resubscribe(); err != nil {
+				log.Printf("broker: reconnect to %s: %v", b.addr, err)
+			}
+		}
+	}
+}
+
+// resubscribe открывает новое соединение подписки; на текущие каналы его подписывает subscribeLoop
+func (b *Redis) resubscribe() (*redisConn, error) {
+	conn, err := b.dial()
+	if err != nil {
+		return nil, err
+	}
+
+	b.subMu.Lock()
+	defer b.subMu.Unlock()
+
+	if b.closed {
+		conn.Close()
+		return nil, ErrClosed
+	}
+	b.sub = conn
+	b.wake()
+	return conn, nil
+}
+
+// read разбирает сообщения соединения подписки до первой ошибки
+func (b *Redis) read(conn *redisConn) error {
+	for {
+		reply, err := readValue(conn.r)
+		if err != nil {
+			return err
+		}
+
+		// Подтверждения subscribe/unsubscribe пропускаем, нужны только ["message", канал, данные]
+		values, ok := reply.([]interface{})
+		if !ok || len(values) != 3 {
+			continue
+		}
+		kind, _ := values[0].([]byte)
+		channel, _ := values[1].([]byte)
+		payload, _ := values[2].([]byte)
+		if string(kind) != "message" {
+			continue
+		}
+
+		var msg Message
+		if err := json.Unmarshal(payload, &msg); err != nil {
+			log.Printf("broker: invalid message on %s: %v", channel, err)
+			continue
+		}
+
+		b.subMu.Lock()
+		handlers := make([]Handler, 0, len(b.handlers[string(channel)]))
+		for _, handler := range b.handlers[string(channel)] {
+			handlers = append(handlers, handler)
+		}
+		b.subMu.Unlock()
+
+		for _, handler := range handlers {
+			handler(msg)
+		}
+	}
+}
+
+// Close закрывает соединения брокера
+func (b *Redis) Close() error {
+	b.subMu.Lock()
+	if b.closed {
+		b.subMu.Unlock()
+		return nil
+	}
+	b.closed = true
+	close(b.done)
+	if b.sub != nil {
+		b.sub.Close()
+	}
+	b.subMu.Unlock()
+
+	b.pubMu.Lock()
+	defer b.pubMu.Unlock()
+	if b.pub != nil {
+		b.pub.Close()
+		b.pub = nil
+	}
+	return nil
+}
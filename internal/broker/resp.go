@@ -0,0 +1,118 @@
+package broker
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Ограничения на входящие RESP-значения, чтобы битый поток не съел память
+const (
+	maxBulkLength  = 16 * 1024 * 1024
+	maxArrayLength = 1024
+)
+
+// respError ошибка, которую вернул сервер (-ERR ...)
+type respError string
+
+func (e respError) Error() string {
+	return string(e)
+}
+
+// writeCommand пишет команду массивом bulk-строк, как ее отправляют клиенты Redis
+func writeCommand(w *bufio.Writer, args ...[]byte) error {
+	fmt.Fprintf(w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(w, "$%d\r\n", len(arg))
+		w.Write(arg)
+		w.WriteString("\r\n")
+	}
+	return w.Flush()
+}
+
+// readValue читает одно RESP-значение. Строки возвращаются как []byte,
+// числа как int64, массивы как []interface{}, nil-значения как nil.
+// Ошибка сервера возвращается значением respError.
+func readValue(r *bufio.Reader) (interface{}, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, errors.New("resp: empty line")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return respError(line[1:]), nil
+	case ':':
+		return strconv.ParseInt(string(line[1:]), 10, 64)
+	case '$':
+		n, err := strconv.Atoi(string(line[1:]))
+		if err != nil || n > maxBulkLength {
+			return nil, fmt.Errorf("resp: invalid bulk length %q", line[1:])
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(string(line[1:]))
+		if err != nil || n > maxArrayLength {
+			return nil, fmt.Errorf("resp: invalid array length %q", line[1:])
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		values := make([]interface{}, n)
+		for i := range values {
+			if values[i], err = readValue(r); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("resp: unknown type %q", line[0])
+}
+
+// readLine читает строку до \r\n без самого разделителя
+func readLine(r *bufio.Reader) ([]byte, error) {
+	line, err := r.ReadSlice('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return nil, errors.New("resp: malformed line")
+	}
+	return append([]byte(nil), line[:len(line)-2]...), nil
+}
+
+// readCommand читает команду клиента: массив bulk-строк
+func readCommand(r *bufio.Reader) ([][]byte, error) {
+	value, err := readValue(r)
+	if err != nil {
+		return nil, err
+	}
+	values, ok := value.([]interface{})
+	if !ok || len(values) == 0 {
+		return nil, errors.New("resp: command must be a non-empty array")
+	}
+
+	args := make([][]byte, len(values))
+	for i, v := range values {
+		arg, ok := v.([]byte)
+		if !ok {
+			return nil, errors.New("resp: command arguments must be strings")
+		}
+		args[i] = arg
+	}
+	return args, nil
+}
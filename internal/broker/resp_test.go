@@ -0,0 +1,138 @@
+package broker
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"single", []string{"PING"}, "*1\r\n$4\r\nPING\r\n"},
+		{"with arguments", []string{"PUBLISH", "board:1", "{}"}, "*3\r\n$7\r\nPUBLISH\r\n$7\r\nboard:1\r\n$2\r\n{}\r\n"},
+		{"empty argument", []string{"SET", ""}, "*2\r\n$3\r\nSET\r\n$0\r\n\r\n"},
+		{"binary argument", []string{"ECHO", "a\r\nb"}, "*2\r\n$4\r\nECHO\r\n$4\r\na\r\nb\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			args := make([][]byte, len(tt.args))
+			for i, arg := range tt.args {
+				args[i] = []byte(arg)
+			}
+			if err := writeCommand(bufio.NewWriter(&out), args...); err != nil {
+				t.Fatalf("writeCommand: %v", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("writeCommand(%q) = %q, want %q", tt.args, out.String(), tt.want)
+			}
+
+			// Записанное читается обратно той же командой
+			got, err := readCommand(bufio.NewReader(&out))
+			if err != nil {
+				t.Fatalf("readCommand: %v", err)
+			}
+			if !reflect.DeepEqual(got, args) {
+				t.Errorf("readCommand = %q, want %q", got, args)
+			}
+		})
+	}
+}
+
+func TestReadValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  interface{}
+	}{
+		{"simple string", "+OK\r\n", []byte("OK")},
+		{"error", "-ERR unknown command\r\n", respError("ERR unknown command")},
+		{"integer", ":42\r\n", int64(42)},
+		{"negative integer", ":-7\r\n", int64(-7)},
+		{"bulk string", "$3\r\nfoo\r\n", []byte("foo")},
+		{"empty bulk string", "$0\r\n\r\n", []byte{}},
+		{"bulk string with CRLF", "$4\r\na\r\nb\r\n", []byte("a\r\nb")},
+		{"nil bulk string", "$-1\r\n", nil},
+		{"empty array", "*0\r\n", []interface{}{}},
+		{"nil array", "*-1\r\n", nil},
+		{"message", "*3\r\n$7\r\nmessage\r\n$7\r\nboard:1\r\n$2\r\n{}\r\n",
+			[]interface{}{[]byte("message"), []byte("board:1"), []byte("{}")}},
+		{"nested array", "*2\r\n:1\r\n*1\r\n+x\r\n", []interface{}{int64(1), []interface{}{[]byte("x")}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bufio.NewReader(strings.NewReader(tt.input))
+			got, err := readValue(r)
+			if err != nil {
+				t.Fatalf("readValue(%q): %v", tt.input, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readValue(%q) = %#v, want %#v", tt.input, got, tt.want)
+			}
+			if r.Buffered() != 0 {
+				t.Errorf("readValue(%q) left %d unread bytes", tt.input, r.Buffered())
+			}
+		})
+	}
+}
+
+func TestReadValueErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  error // nil - подходит любая ошибка
+	}{
+		{"eof", "", io.EOF},
+		{"truncated bulk string", "$5\r\nab", io.ErrUnexpectedEOF},
+		{"truncated array", "*2\r\n:1\r\n", io.EOF},
+		{"empty line", "\r\n", nil},
+		{"missing CR", "+OK\n", nil},
+		{"unknown type", "?x\r\n", nil},
+		{"invalid integer", ":abc\r\n", nil},
+		{"invalid bulk length", "$x\r\n", nil},
+		{"bulk too long", "$99999999\r\n", nil},
+		{"array too long", "*2000\r\n", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readValue(bufio.NewReader(strings.NewReader(tt.input)))
+			if err == nil {
+				t.Fatalf("readValue(%q) succeeded, want error", tt.input)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("readValue(%q) error = %v, want %v", tt.input, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadCommandErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"not an array", "+PING\r\n"},
+		{"empty array", "*0\r\n"},
+		{"nil array", "*-1\r\n"},
+		{"integer argument", "*2\r\n$3\r\nGET\r\n:1\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := readCommand(bufio.NewReader(strings.NewReader(tt.input))); err == nil {
+				t.Errorf("readCommand(%q) succeeded, want error", tt.input)
+			}
+		})
+	}
+}
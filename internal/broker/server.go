@@ -0,0 +1,220 @@
+package broker
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+)
+
+// Server минимальный сервер pub/sub, совместимый с протоколом Redis.
+// Поддерживает PING, AUTH, PUBLISH, SUBSCRIBE, UNSUBSCRIBE и QUIT - ровно то,
+// что нужно брокеру Redis. Позволяет запустить несколько реплик без настоящего Redis.
+type Server struct {
+	mu       sync.Mutex
+	channels map[string]map[*serverConn]bool // канал -> подписчики
+	listener net.Listener
+}
+
+// serverConn подключение клиента к Server
+type serverConn struct {
+	conn     net.Conn
+	mu       sync.Mutex // Запись из своей горутины и из PUBLISH других клиентов
+	w        *bufio.Writer
+	channels map[string]bool
+}
+
+// NewServer создает сервер pub/sub
+func NewServer() *Server {
+	return &Server{channels: make(map[string]map[*serverConn]bool)}
+}
+
+// ListenAndServe принимает подключения по адресу addr до вызова Close
+func (s *Server) ListenAndServe(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(listener)
+}
+
+// Serve принимает подключения из listener до вызова Close
+func (s *Server) Serve(listener net.Listener) error {
+	s.mu.Lock()
+	s.listener = listener
+	s.mu.Unlock()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go s.serve(conn)
+	}
+}
+
+// Addr адрес, на котором сервер принимает подключения
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// Close прекращает прием подключений
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Close()
+}
+
+// serve обрабатывает команды одного клиента
+func (s *Server) serve(conn net.Conn) {
+	c := &serverConn{
+		conn:     conn,
+		w:        bufio.NewWriter(conn),
+		channels: make(map[string]bool),
+	}
+	defer func() {
+		s.unsubscribeAll(c)
+		conn.Close()
+	}()
+
+	r := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+
+		switch strings.ToUpper(string(args[0])) {
+		case "PING":
+			if len(c.channels) > 0 {
+				c.write("*2\r\n$4\r\npong\r\n$0\r\n\r\n")
+			} else {
+				c.write("+PONG\r\n")
+			}
+		case "AUTH":
+			// Встроенный сервер не проверяет пароль, но клиенты с паролем должны работать
+			c.write("+OK\r\n")
+		case "PUBLISH":
+			if len(args) != 3 {
+				c.writeError("wrong number of arguments for 'publish' command")
+				continue
+			}
+			c.write(fmt.Sprintf(":%d\r\n", s.publish(string(args[1]), args[2])))
+		case "SUBSCRIBE":
+			if len(args) < 2 {
+				c.writeError("wrong number of arguments for 'subscribe' command")
+				continue
+			}
+			for _, channel := range args[1:] {
+				s.subscribe(c, string(channel))
+			}
+		case "UNSUBSCRIBE":
+			channels := args[1:]
+			if len(channels) == 0 {
+				for channel := range c.subscribed() {
+					channels = append(channels, []byte(channel))
+				}
+			}
+			for _, channel := range channels {
+				s.unsubscribe(c, string(channel))
+			}
+		case "QUIT":
+			c.write("+OK\r\n")
+			return
+		default:
+			c.writeError(fmt.Sprintf("unknown command '%s'", args[0]))
+		}
+	}
+}
+
+// publish рассылает данные подписчикам канала и возвращает их число
+func (s *Server) publish(channel string, data []byte) int {
+	s.mu.Lock()
+	subscribers := make([]*serverConn, 0, len(s.channels[channel]))
+	for c := range s.channels[channel] {
+		subscribers = append(subscribers, c)
+	}
+	s.mu.Unlock()
+
+	for _, c := range subscribers {
+		c.write(fmt.Sprintf("*3\r\n$7\r\nmessage\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n", len(channel), channel, len(data), data))
+	}
+	return len(subscribers)
+}
+
+// subscribe подписывает клиента на канал и подтверждает подписку
+func (s *Server) subscribe(c *serverConn, channel string) {
+	s.mu.Lock()
+	if s.channels[channel] == nil {
+		s.channels[channel] = make(map[*serverConn]bool)
+	}
+	s.channels[channel][c] = true
+	c.channels[channel] = true
+	count := len(c.channels)
+	s.mu.Unlock()
+
+	c.write(fmt.Sprintf("*3\r\n$9\r\nsubscribe\r\n$%d\r\n%s\r\n:%d\r\n", len(channel), channel, count))
+}
+
+// unsubscribe отписывает клиента от канала и подтверждает отписку
+func (s *Server) unsubscribe(c *serverConn, channel string) {
+	s.mu.Lock()
+	s.removeLocked(c, channel)
+	count := len(c.channels)
+	s.mu.Unlock()
+
+	c.write(fmt.Sprintf("*3\r\n$11\r\nunsubscribe\r\n$%d\r\n%s\r\n:%d\r\n", len(channel), channel, count))
+}
+
+// unsubscribeAll отписывает отключившегося клиента от всех каналов
+func (s *Server) unsubscribeAll(c *serverConn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for channel := range c.channels {
+		s.removeLocked(c, channel)
+	}
+}
+
+// removeLocked удаляет подписку клиента, вызывающий держит s.mu
+func (s *Server) removeLocked(c *serverConn, channel string) {
+	delete(c.channels, channel)
+	delete(s.channels[channel], c)
+	if len(s.channels[channel]) == 0 {
+		delete(s.channels, channel)
+	}
+}
+
+// subscribed копия каналов клиента
+func (c *serverConn) subscribed() map[string]bool {
+	channels := make(map[string]bool, len(c.channels))
+	for channel := range c.channels {
+		channels[channel] = true
+	}
+	return channels
+}
+
+// write отправляет клиенту готовый RESP-ответ
+func (c *serverConn) write(reply string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.w.WriteString(reply)
+	if err := c.w.Flush(); err != nil {
+		log.Printf("broker server: write to %s: %v", c.conn.RemoteAddr(), err)
+		c.conn.Close()
+	}
+}
+
+// writeError отправляет клиенту ошибку
+func (c *serverConn) writeError(message string) {
+	c.write("-ERR " + message + "\r\n")
+}
@@ -0,0 +1,112 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock источник текущего времени
+type Clock interface {
+	Now() time.Time
+}
+
+// Controllable часы, которые можно заморозить, переставить или сдвинуть вперед.
+// Незамороженные часы идут вместе с системными со сдвигом offset.
+type Controllable struct {
+	mu       sync.RWMutex
+	offset   time.Duration
+	frozen   bool
+	frozenAt time.Time
+}
+
+// New создает часы, совпадающие с системными
+func New() *Controllable {
+	return &Controllable{}
+}
+
+// Now возвращает текущее время с учетом заморозки и сдвига
+func (c *Controllable) Now() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.frozen {
+		return c.frozenAt
+	}
+	return time.Now().Add(c.offset)
+}
+
+// Frozen сообщает, заморожены ли часы
+func (c *Controllable) Frozen() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.frozen
+}
+
+// Freeze останавливает часы на текущем моменте
+func (c *Controllable) Freeze() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.frozen {
+		c.frozenAt = time.Now().Add(c.offset)
+		c.frozen = true
+	}
+}
+
+// Unfreeze запускает часы с того момента, на котором они стояли
+func (c *Controllable) Unfreeze() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.frozen {
+		c.offset = c.frozenAt.Sub(time.Now())
+		c.frozen = false
+	}
+}
+
+// Set переставляет часы на указанное время
+func (c *Controllable) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.frozen {
+		c.frozenAt = t
+	} else {
+		c.offset = t.Sub(time.Now())
+	}
+}
+
+// Advance сдвигает часы вперед на d
+func (c *Controllable) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.frozen {
+		c.frozenAt = c.frozenAt.Add(d)
+	} else {
+		c.offset += d
+	}
+}
+
+// Reset возвращает часы к системному времени
+func (c *Controllable) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.offset = 0
+	c.frozen = false
+	c.frozenAt = time.Time{}
+}
+
+var defaultClock = New()
+
+// Default возвращает часы, используемые всем приложением
+func Default() *Controllable {
+	return defaultClock
+}
+
+// Now возвращает текущее время часов приложения
+func Now() time.Time {
+	return defaultClock.Now()
+}
@@ -0,0 +1,53 @@
+package generator
+
+// Словари для генерации правдоподобных данных. Имена только латиницей,
+// чтобы проходить utils.ValidateRegistration.
+
+var firstNames = []string{
+	"Alice", "Boris", "Clara", "Daniel", "Elena", "Felix", "Greta", "Hugo",
+	"Irina", "Jakob", "Katya", "Leon", "Maria", "Nikita", "Olga", "Pavel",
+	"Quinn", "Roman", "Sofia", "Timur", "Ulyana", "Victor", "Wanda", "Xenia",
+	"Yuri", "Zoe", "Anton", "Bella", "Denis", "Emma", "Gleb", "Lina",
+}
+
+var lastNames = []string{
+	"Ivanov", "Petrova", "Smirnov", "Kuznetsova", "Popov", "Volkova", "Sokolov",
+	"Lebedeva", "Kozlov", "Novikova", "Morozov", "Orlova", "Miller", "Schmidt",
+	"Fischer", "Weber", "Martin", "Bernard", "Dubois", "Rossi", "Bianchi",
+	"Garcia", "Lopez", "Nowak", "Kowalski", "Horvat", "Novak", "Jensen",
+}
+
+var boardTopics = []string{
+	"Sprint Planning", "Biology Notes", "Product Roadmap", "Team Retro",
+	"Physics Lab", "Marketing Ideas", "History Timeline", "User Journey",
+	"Design Review", "Algebra Homework", "Travel Plans", "Reading List",
+	"Hackathon Brainstorm", "Onboarding Checklist", "Architecture Sketch",
+	"Chemistry Experiment", "Weekly Sync", "Mood Board", "Exam Prep",
+	"Research Questions",
+}
+
+var boardQualifiers = []string{
+	"Q1", "Q2", "Draft", "Final", "v2", "Group A", "Group B", "Spring",
+	"Autumn", "Backup", "Week 3", "Week 7", "Ideas", "Shared",
+}
+
+var textSnippets = []string{
+	"Discuss with the team",
+	"Deadline on Friday",
+	"Needs more research",
+	"Key idea: keep it simple",
+	"Open question",
+	"TODO: add examples",
+	"Blocked by review",
+	"Great progress!",
+	"Compare with last year",
+	"Collect feedback from users",
+}
+
+var palette = []string{
+	"#f44336", "#e91e63", "#9c27b0", "#3f51b5", "#2196f3", "#009688",
+	"#4caf50", "#ffc107", "#ff9800", "#795548", "#607d8b", "#000000",
+}
+
+// objectTypes перечисляет все типы BoardObject
+var objectTypes = []string{"text", "image", "rectangle", "circle", "line"}
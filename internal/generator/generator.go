@@ -0,0 +1,358 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/alexl/go-fake-api/internal/idgen"
+	"github.com/alexl/go-fake-api/internal/models"
+	"github.com/alexl/go-fake-api/internal/storage"
+	"github.com/alexl/go-fake-api/internal/utils"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Размер холста, на котором раскладываются объекты
+const (
+	canvasWidth  = 1920
+	canvasHeight = 1080
+)
+
+// DefaultPassword пароль всех сгенерированных пользователей
+const DefaultPassword = "Password123!"
+
+// Ограничения на размер генерации
+const (
+	MaxUsers           = 1000
+	MaxBoardsPerUser   = 20
+	MaxObjectsPerBoard = 200
+)
+
+// epoch точка отсчета для created_at, чтобы результат не зависел от часов
+var epoch = time.Date(2026, time.January, 1, 9, 0, 0, 0, time.UTC)
+
+// Options параметры генерации
+type Options struct {
+	Users           int    `json:"users"`
+	BoardsPerUser   int    `json:"boards_per_user"`
+	ObjectsPerBoard int    `json:"objects_per_board"`
+	Seed            *int64 `json:"seed,omitempty"`
+}
+
+// User сгенерированный пользователь вместе с паролем для входа
+type User struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// Board краткая информация о сгенерированной доске
+type Board struct {
+	ID       string `json:"id"`
+	Hash     string `json:"hash"`
+	Name     string `json:"name"`
+	OwnerID  int    `json:"owner_id"`
+	IsPublic bool   `json:"is_public"`
+	Likes    int    `json:"likes"`
+	Objects  int    `json:"objects"`
+}
+
+// Result результат генерации
+type Result struct {
+	Seed   int64   `json:"seed"`
+	Users  []User  `json:"users"`
+	Boards []Board `json:"boards"`
+}
+
+// Validate проверяет параметры генерации
+func (o Options) Validate() map[string][]string {
+	errors := make(map[string][]string)
+
+	if o.Users < 1 || o.Users > MaxUsers {
+		errors["users"] = append(errors["users"], fmt.Sprintf("users must be between 1 and %d", MaxUsers))
+	}
+	if o.BoardsPerUser < 0 || o.BoardsPerUser > MaxBoardsPerUser {
+		errors["boards_per_user"] = append(errors["boards_per_user"], fmt.Sprintf("boards_per_user must be between 0 and %d", MaxBoardsPerUser))
+	}
+	if o.ObjectsPerBoard < 0 || o.ObjectsPerBoard > MaxObjectsPerBoard {
+		errors["objects_per_board"] = append(errors["objects_per_board"], fmt.Sprintf("objects_per_board must be between 0 and %d", MaxObjectsPerBoard))
+	}
+
+	return errors
+}
+
+// generator хранит состояние одного прогона генерации
+type generator struct {
+	store storage.Storage
+	rng   *rand.Rand
+	opts  Options
+	users []User
+}
+
+// Generate создает пользователей и доски в хранилище.
+// При одинаковом seed и пустом хранилище результат полностью совпадает.
+func Generate(store storage.Storage, opts Options) (*Result, error) {
+	if errs := opts.Validate(); len(errs) > 0 {
+		return nil, errors.New("invalid generator options")
+	}
+
+	seed := time.Now().UnixNano()
+	if opts.Seed != nil {
+		seed = *opts.Seed
+	}
+
+	g := &generator{
+		store: store,
+		rng:   rand.New(rand.NewSource(seed)),
+		opts:  opts,
+	}
+
+	result := &Result{Seed: seed}
+
+	// Хеш пароля общий для всех: bcrypt дорогой, а пароль одинаковый
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(DefaultPassword), bcrypt.MinCost)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < opts.Users; i++ {
+		user, err := g.createUser(string(hashedPassword))
+		if err != nil {
+			return nil, err
+		}
+		g.users = append(g.users, User{
+			ID:       user.ID,
+			Name:     user.Name,
+			Email:    user.Email,
+			Password: DefaultPassword,
+		})
+	}
+	result.Users = g.users
+
+	for _, owner := range result.Users {
+		boards := 0
+		if opts.BoardsPerUser > 0 {
+			boards = 1 + g.rng.Intn(opts.BoardsPerUser)
+		}
+		for i := 0; i < boards; i++ {
+			board, err := g.createBoard(owner.ID)
+			if err != nil {
+				return nil, err
+			}
+			result.Boards = append(result.Boards, Board{
+				ID:       board.ID,
+				Hash:     board.Hash,
+				Name:     board.Name,
+				OwnerID:  board.OwnerID,
+				IsPublic: board.IsPublic,
+				Objects:  len(board.Objects),
+			})
+		}
+	}
+
+	if err := g.distributeLikes(result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// createUser создает пользователя с уникальным email
+func (g *generator) createUser(hashedPassword string) (*models.User, error) {
+	first := firstNames[g.rng.Intn(len(firstNames))]
+	last := lastNames[g.rng.Intn(len(lastNames))]
+
+	req := models.RegistrationRequest{
+		Name:     first + " " + last,
+		Password: DefaultPassword,
+	}
+
+	base := strings.ToLower(first + "." + last)
+	for n := 0; ; n++ {
+		req.Email = base + "@example.com"
+		if n > 0 {
+			req.Email = fmt.Sprintf("%s%d@example.com", base, n)
+		}
+		if _, err := g.store.GetUserByEmail(req.Email); err != nil {
+			break
+		}
+	}
+
+	if validationErrors := utils.ValidateRegistration(req); len(validationErrors) > 0 {
+		return nil, fmt.Errorf("generated user is invalid: %v", validationErrors)
+	}
+
+	user := &models.User{
+		Name:      req.Name,
+		Email:     req.Email,
+		Password:  hashedPassword,
+		CreatedAt: epoch.Add(-time.Duration(g.rng.Intn(90*24)) * time.Hour),
+	}
+
+	if err := g.store.CreateUser(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// createBoard создает доску с объектами и случайными соавторами
+func (g *generator) createBoard(ownerID int) (*models.Board, error) {
+	name := boardTopics[g.rng.Intn(len(boardTopics))]
+	if g.rng.Intn(2) == 0 {
+		name += " " + boardQualifiers[g.rng.Intn(len(boardQualifiers))]
+	}
+
+	// Повторный запуск с тем же seed не должен затирать уже созданные доски
+	boardID := fmt.Sprintf("board-%d", g.rng.Int63())
+	for {
+		if _, err := g.store.GetBoardByID(boardID); err != nil {
+			break
+		}
+		boardID = fmt.Sprintf("board-%d", g.rng.Int63())
+	}
+
+	board := &models.Board{
+		ID:        boardID,
+		Hash:      idgen.Hash(),
+		Name:      name,
+		OwnerID:   ownerID,
+		IsPublic:  g.rng.Intn(10) < 6,
+		Objects:   g.layoutObjects(),
+		CreatedAt: epoch.Add(-time.Duration(g.rng.Intn(60*24*60)) * time.Minute),
+	}
+
+	if err := g.store.CreateBoard(board); err != nil {
+		return nil, err
+	}
+
+	// Примерно треть досок расшарена нескольким пользователям
+	if len(g.users) > 1 && g.rng.Intn(3) == 0 {
+		collaborators := 1 + g.rng.Intn(3)
+		for i := 0; i < collaborators; i++ {
+			collaborator := g.users[g.rng.Intn(len(g.users))]
+			if collaborator.ID == ownerID {
+				continue
+			}
+			if err := g.store.AddBoardAccess(board.ID, collaborator.ID, models.RoleEditor); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return board, nil
+}
+
+// layoutObjects раскладывает объекты по сетке холста без наложений.
+// Первые объекты покрывают все типы, остальные выбираются случайно.
+func (g *generator) layoutObjects() map[string]models.BoardObject {
+	objects := make(map[string]models.BoardObject)
+	count := g.opts.ObjectsPerBoard
+	if count == 0 {
+		return objects
+	}
+	if count > len(objectTypes) {
+		count = len(objectTypes) + g.rng.Intn(count-len(objectTypes)+1)
+	}
+
+	cols := 1
+	for cols*cols < count {
+		cols++
+	}
+	rows := (count + cols - 1) / cols
+	cellW := float64(canvasWidth) / float64(cols)
+	cellH := float64(canvasHeight) / float64(rows)
+
+	for i := 0; i < count; i++ {
+		objType := objectTypes[i%len(objectTypes)]
+		if i >= len(objectTypes) {
+			objType = objectTypes[g.rng.Intn(len(objectTypes))]
+		}
+
+		// Объект занимает 50-90% ячейки и случайно сдвинут внутри нее
+		w := cellW * (0.5 + 0.4*g.rng.Float64())
+		h := cellH * (0.5 + 0.4*g.rng.Float64())
+		x := float64(i%cols)*cellW + (cellW-w)*g.rng.Float64()
+		y := float64(i/cols)*cellH + (cellH-h)*g.rng.Float64()
+
+		// ID из общего генератора: иначе последовательные ID созданных позже объектов
+		// совпадут с ID сгенерированных. Генератор по времени может выдать одинаковые ID подряд.
+		id := idgen.ObjectID()
+		for _, taken := objects[id]; taken; _, taken = objects[id] {
+			id = idgen.ObjectID()
+		}
+		obj := models.BoardObject{
+			ID:     id,
+			Type:   objType,
+			X:      round(x),
+			Y:      round(y),
+			Width:  round(w),
+			Height: round(h),
+			Color:  palette[g.rng.Intn(len(palette))],
+			Z:      i + 1,
+		}
+
+		switch objType {
+		case "text":
+			obj.Content = textSnippets[g.rng.Intn(len(textSnippets))]
+			obj.Height = round(h / 3)
+			obj.FontSize = float64(12 + 2*g.rng.Intn(10))
+		case "image":
+			obj.Content = fmt.Sprintf("https://picsum.photos/seed/%d/%d/%d", g.rng.Intn(100000), int(w), int(h))
+			obj.Color = ""
+		case "circle":
+			// Круг вписан в квадрат
+			if obj.Width > obj.Height {
+				obj.Width = obj.Height
+			} else {
+				obj.Height = obj.Width
+			}
+		case "line":
+			obj.Height = 0
+			obj.Rotation = float64(g.rng.Intn(8) * 45)
+			obj.Points = []float64{0, 0, obj.Width, 0}
+		default:
+			if g.rng.Intn(4) == 0 {
+				obj.Rotation = float64(g.rng.Intn(4) * 15)
+			}
+		}
+
+		objects[obj.ID] = obj
+	}
+
+	return objects
+}
+
+// distributeLikes раздает лайки по закону Ципфа: немногие доски популярны,
+// у большинства лайков мало или нет
+func (g *generator) distributeLikes(result *Result) error {
+	users := len(result.Users)
+	if users == 0 || len(result.Boards) == 0 {
+		return nil
+	}
+
+	zipf := rand.NewZipf(g.rng, 1.5, 1, uint64(users))
+	for i := range result.Boards {
+		board := &result.Boards[i]
+		if !board.IsPublic {
+			continue
+		}
+
+		likes := int(zipf.Uint64())
+		for _, idx := range g.rng.Perm(users)[:likes] {
+			if _, err := g.store.LikeBoard(board.ID, result.Users[idx].ID); err != nil {
+				return err
+			}
+		}
+		board.Likes = likes
+	}
+
+	return nil
+}
+
+// round округляет координату до десятых
+func round(v float64) float64 {
+	return float64(int(v*10)) / 10
+}
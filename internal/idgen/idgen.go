@@ -0,0 +1,146 @@
+package idgen
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	mrand "math/rand"
+	"sync"
+	"time"
+)
+
+// Ограничения на длину публичного хеша
+const (
+	DefaultHashLength = 22 // ~131 бит энтропии
+	MinHashLength     = 16
+	MaxHashLength     = 64
+)
+
+// hashAlphabet алфавит хешей, безопасный для URL
+const hashAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// Generator выдает идентификаторы досок, объектов и публичные хеши
+type Generator interface {
+	BoardID() string
+	ObjectID() string
+	Hash() string
+}
+
+// Random генератор по умолчанию: ID из системного времени, хеши из crypto/rand
+type Random struct {
+	HashLength int
+}
+
+// NewRandom создает генератор с хешами заданной длины
+func NewRandom(hashLength int) *Random {
+	return &Random{HashLength: hashLength}
+}
+
+// BoardID возвращает ID доски
+func (g *Random) BoardID() string {
+	return fmt.Sprintf("board-%d", time.Now().UnixNano())
+}
+
+// ObjectID возвращает ID объекта доски
+func (g *Random) ObjectID() string {
+	return fmt.Sprintf("obj-%d", time.Now().UnixNano())
+}
+
+// Hash возвращает непредсказуемый публичный хеш доски
+func (g *Random) Hash() string {
+	max := big.NewInt(int64(len(hashAlphabet)))
+	b := make([]byte, g.HashLength)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			// Без источника случайности выдавать предсказуемые ссылки нельзя
+			panic(fmt.Sprintf("idgen: crypto/rand failed: %v", err))
+		}
+		b[i] = hashAlphabet[n.Int64()]
+	}
+	return string(b)
+}
+
+// Sequential детерминированный генератор: ID из счетчика, хеши из PRNG с заданным seed
+type Sequential struct {
+	mu         sync.Mutex
+	counter    int
+	objects    int
+	rng        *mrand.Rand
+	hashLength int
+}
+
+// NewSequential создает детерминированный генератор
+func NewSequential(seed int64, hashLength int) *Sequential {
+	return &Sequential{
+		rng:        mrand.New(mrand.NewSource(seed)),
+		hashLength: hashLength,
+	}
+}
+
+// BoardID возвращает следующий ID доски
+func (g *Sequential) BoardID() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.counter++
+	return fmt.Sprintf("board-%d", g.counter)
+}
+
+// ObjectID возвращает следующий ID объекта
+func (g *Sequential) ObjectID() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.objects++
+	return fmt.Sprintf("obj-%d", g.objects)
+}
+
+// Hash возвращает следующий хеш из PRNG
+func (g *Sequential) Hash() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	b := make([]byte, g.hashLength)
+	for i := range b {
+		b[i] = hashAlphabet[g.rng.Intn(len(hashAlphabet))]
+	}
+	return string(b)
+}
+
+var (
+	current Generator = NewRandom(DefaultHashLength)
+	mu      sync.RWMutex
+)
+
+// SetDefault заменяет генератор, используемый приложением
+func SetDefault(g Generator) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	current = g
+}
+
+// BoardID возвращает ID доски от генератора приложения
+func BoardID() string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return current.BoardID()
+}
+
+// ObjectID возвращает ID объекта от генератора приложения
+func ObjectID() string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return current.ObjectID()
+}
+
+// Hash возвращает публичный хеш от генератора приложения
+func Hash() string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return current.Hash()
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/alexl/go-fake-api/internal/clock"
 	"github.com/alexl/go-fake-api/internal/storage"
 	"github.com/alexl/go-fake-api/internal/utils"
 )
@@ -34,7 +35,7 @@ func AuthMiddleware(store storage.Storage) func(http.Handler) http.Handler {
 
 			// Получаем пользователя по токену
 			user, err := store.GetUserByToken(token)
-			if err != nil {
+			if err != nil || user.TokenExpired(clock.Now()) {
 				utils.RespondWithError(w, http.StatusForbidden, "Login failed", nil)
 				return
 			}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Ограничения загружаемых изображений
+const (
+	MaxAssetSize      = 10 << 20 // Байт в исходном файле
+	MaxAssetDimension = 8192     // Пикселей по ширине и высоте
+	MaxAssetPixels    = 40e6     // Пикселей всего: защита от распаковки огромных картинок
+)
+
+// Варианты изображения: наибольшая сторона уменьшается до указанного размера
+var AssetVariantSizes = []struct {
+	Name string
+	Size int
+}{
+	{Name: "thumb", Size: 256},
+	{Name: "medium", Size: 1024},
+}
+
+// Asset загруженное изображение доски. Файл доступен без авторизации по url,
+// объекты image ссылаются на него в content.
+type Asset struct {
+	ID         string         `json:"id"`
+	BoardID    string         `json:"board_id"`
+	UploaderID int            `json:"uploader_id"`
+	MimeType   string         `json:"mime_type"`
+	Size       int64          `json:"size"`
+	Width      int            `json:"width"`
+	Height     int            `json:"height"`
+	URL        string         `json:"url"`
+	Variants   []AssetVariant `json:"variants"` // Только меньше оригинала
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+// AssetVariant уменьшенная копия изображения
+type AssetVariant struct {
+	Name     string `json:"name"` // thumb, medium
+	MimeType string `json:"mime_type"`
+	Size     int64  `json:"size"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	URL      string `json:"url"`
+}
+
+// AssetCollection результат сборки неиспользуемых изображений
+type AssetCollection struct {
+	Collected []string `json:"collected"` // ID удаленных изображений
+}
@@ -1,42 +1,91 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 )
 
 // Board представляет интерактивную доску
 type Board struct {
-	ID        string                 `json:"id"`
-	Hash      string                 `json:"hash"` // Публичный хеш для доступа без авторизации
-	Name      string                 `json:"name"`
-	OwnerID   int                    `json:"owner_id"`
-	IsPublic  bool                   `json:"is_public"`
-	Likes     int                    `json:"likes"`
-	Objects   map[string]BoardObject   `json:"objects"` // map[object_id]Object
-	CreatedAt time.Time              `json:"created_at"`
+	ID          string                 `json:"id"`
+	Hash        string                 `json:"hash"` // Публичный хеш для доступа без авторизации
+	Name        string                 `json:"name"`
+	OwnerID     int                    `json:"owner_id"`
+	IsPublic    bool                   `json:"is_public"`
+	LinkSharing bool                   `json:"link_sharing"` // Доступ к приватной доске по хешу
+	IsTemplate  bool                   `json:"is_template"`  // Доска опубликована в галерее шаблонов
+	Likes       int                    `json:"likes"`
+	Tags        []string               `json:"tags"`
+	Layers      []Layer                `json:"layers"`  // Снизу вверх; объекты без слоя лежат под всеми слоями
+	Objects     map[string]BoardObject `json:"objects"` // map[object_id]Object
+	CreatedAt   time.Time              `json:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at"` // Последнее изменение объектов, чата или комментариев
+
+	// Личные настройки пользователя, заполняются только в списке GET /boards
+	Favorite bool `json:"favorite,omitempty"`
+	FolderID int  `json:"folder_id,omitempty"`
 }
 
+// Типы объектов доски
+const (
+	ObjectText      = "text"
+	ObjectImage     = "image"
+	ObjectRectangle = "rectangle"
+	ObjectCircle    = "circle"
+	ObjectLine      = "line"
+	ObjectPath      = "path"      // Рисунок от руки
+	ObjectConnector = "connector" // Линия между двумя объектами, геометрию считает сервер
+	ObjectGroup     = "group"     // Создается только операцией группировки
+)
+
+// Ограничения объектов доски
+const (
+	MaxBoardObjects  = 5000  // Объектов на одной доске
+	MaxObjectIDLen   = 64    // Длина ID объекта
+	MaxObjectContent = 10000 // Символов текста
+	MaxImageURLLen   = 2048
+	MaxCoordinate    = 1e6 // Модуль координат x и y
+	MaxObjectSize    = 1e5 // Ширина и высота
+	MinFontSize      = 1
+	MaxFontSize      = 512
+	MaxLinePoints    = 1000  // Точек линии, то есть пар чисел в points
+	MaxPathInput     = 10000 // Точек пути в запросе
+	MaxPathPoints    = 500   // Точек пути после упрощения на сервере
+	PathTolerance    = 0.5   // Отклонение, в пределах которого точки пути отбрасываются
+)
+
 // BoardObject представляет объект на доске
 type BoardObject struct {
-	ID         string  `json:"id"`
-	Type       string  `json:"type"` // text, image, rectangle, circle, line
-	X          float64 `json:"x"`
-	Y          float64 `json:"y"`
-	Width      float64 `json:"width"`
-	Height     float64 `json:"height"`
-	Rotation   float64 `json:"rotation"`
-	Content    string  `json:"content,omitempty"` // Текст или URL изображения
-	Color      string  `json:"color,omitempty"`
-	FocusedBy  *int    `json:"focused_by,omitempty"`   // ID пользователя, захватившего объект
-	FocusedAt  *time.Time `json:"focused_at,omitempty"`
-	OwnerName  string  `json:"owner_name,omitempty"`   // Имя пользователя, захватившего объект
+	ID        string     `json:"id"`
+	Type      string     `json:"type"` // text, image, rectangle, circle, line, path, connector, group
+	X         float64    `json:"x"`
+	Y         float64    `json:"y"`
+	Width     float64    `json:"width"`
+	Height    float64    `json:"height"`
+	Rotation  float64    `json:"rotation"`
+	Content   string     `json:"content,omitempty"` // Текст или URL изображения
+	Color     string     `json:"color,omitempty"`
+	FontSize  float64    `json:"font_size,omitempty"`  // Только у text
+	Points    []float64  `json:"points,omitempty"`     // У line, path и connector: x1, y1, x2, y2, ... относительно x и y
+	From      string     `json:"from,omitempty"`       // Только у connector: ID начального объекта
+	To        string     `json:"to,omitempty"`         // Только у connector: ID конечного объекта
+	LayerID   string     `json:"layer_id,omitempty"`   // Пустой - объект вне слоев
+	Z         int        `json:"z"`                    // Порядок наложения, больше - выше; меняется только операцией reorder
+	GroupID   string     `json:"group_id,omitempty"`   // Группа, в которую входит объект
+	Children  []string   `json:"children,omitempty"`   // Только у group: ID входящих объектов
+	Locked    bool       `json:"locked,omitempty"`     // Закреплен владельцем доски, менять нельзя
+	FocusedBy *int       `json:"focused_by,omitempty"` // ID пользователя, захватившего объект
+	FocusedAt *time.Time `json:"focused_at,omitempty"`
+	OwnerName string     `json:"owner_name,omitempty"` // Имя пользователя, захватившего объект
 }
 
 // BoardAccess представляет права доступа к доске
 type BoardAccess struct {
 	BoardID string `json:"board_id"`
 	UserID  int    `json:"user_id"`
+	Name    string `json:"name"`
 	Email   string `json:"email"`
+	Role    string `json:"role"`
 }
 
 // Like представляет лайк доске
@@ -47,18 +96,76 @@ type Like struct {
 
 // BoardCreateRequest запрос на создание доски
 type BoardCreateRequest struct {
+	Name        string `json:"name"`
+	IsPublic    bool   `json:"is_public"`
+	LinkSharing bool   `json:"link_sharing"`
+	TemplateID  string `json:"template_id,omitempty"` // Создать доску копией шаблона
+}
+
+// BoardDuplicateRequest запрос на копирование доски, пустое название - "<название> (copy)"
+type BoardDuplicateRequest struct {
 	Name     string `json:"name"`
 	IsPublic bool   `json:"is_public"`
 }
 
+// BoardTemplateRequest запрос на публикацию доски в галерее шаблонов или снятие с нее
+type BoardTemplateRequest struct {
+	Enabled bool `json:"enabled"`
+}
+
+// BoardLinkRequest запрос на включение/выключение доступа по ссылке
+type BoardLinkRequest struct {
+	Enabled bool `json:"enabled"`
+}
+
 // BoardShareRequest запрос на предоставление доступа
 type BoardShareRequest struct {
 	Email string `json:"email"`
+	Role  string `json:"role,omitempty"` // editor (по умолчанию) или viewer
 }
 
 // WSMessage структура сообщения WebSocket
 type WSMessage struct {
-	Type    string      `json:"type"`    // object_update, object_focus, object_blur, object_delete
-	BoardID string      `json:"board_id"`
-	Payload interface{} `json:"payload"`
+	Type      string      `json:"type"` // object_update, object_focus, object_blur, object_delete
+	BoardID   string      `json:"board_id"`
+	RequestID string      `json:"request_id,omitempty"` // Только в ack и error: ID запроса клиента
+	Payload   interface{} `json:"payload"`
+}
+
+// WSClientMessage входящее сообщение клиента, payload разбирается по типу сообщения
+type WSClientMessage struct {
+	Type      string          `json:"type"`
+	RequestID string          `json:"request_id,omitempty"` // Необязательный ID, генерируется клиентом
+	Payload   json.RawMessage `json:"payload"`
+}
+
+// ObjectsBatch пакет изменений объектов, применяется целиком или не применяется вовсе
+type ObjectsBatch struct {
+	Updates []BoardObject `json:"updates"`
+	Deletes []string      `json:"deletes"`
+}
+
+// WSError payload сообщения error
+type WSError struct {
+	Code    string              `json:"code"` // forbidden, locked, invalid_payload, not_found, ...
+	Message string              `json:"message"`
+	Errors  map[string][]string `json:"errors,omitempty"` // Нарушения по полям, как в ответе 422 REST
+}
+
+// Presence участники доски, рассылается при подключении и отключении
+type Presence struct {
+	Users      []PresenceUser `json:"users"`
+	Spectators int            `json:"spectators"` // Анонимные зрители публичной доски
+}
+
+// PresenceUser авторизованный участник доски
+type PresenceUser struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// DisconnectPayload причина отключения клиента сервером
+type DisconnectPayload struct {
+	Code   int    `json:"code"`   // Код закрытия WebSocket
+	Reason string `json:"reason"` // session_revoked, token_expired, access_revoked, board_unavailable
 }
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+)
+
+// MaxMessageLength максимальная длина сообщения чата или комментария в символах
+const MaxMessageLength = 4000
+
+// ChatMessage сообщение чата доски
+type ChatMessage struct {
+	ID         int        `json:"id"`
+	BoardID    string     `json:"board_id"`
+	AuthorID   int        `json:"author_id"`
+	AuthorName string     `json:"author_name"`
+	Text       string     `json:"text"`
+	Mentions   []int      `json:"mentions"` // ID упомянутых участников доски
+	CreatedAt  time.Time  `json:"created_at"`
+	EditedAt   *time.Time `json:"edited_at,omitempty"`
+}
+
+// Comment комментарий к объекту доски. Ответы ссылаются на корневой комментарий ветки.
+type Comment struct {
+	ID         int        `json:"id"`
+	BoardID    string     `json:"board_id"`
+	ObjectID   string     `json:"object_id"`
+	ParentID   int        `json:"parent_id,omitempty"` // 0 - корень ветки
+	AuthorID   int        `json:"author_id"`
+	AuthorName string     `json:"author_name"`
+	Text       string     `json:"text"`
+	Mentions   []int      `json:"mentions"`
+	CreatedAt  time.Time  `json:"created_at"`
+	EditedAt   *time.Time `json:"edited_at,omitempty"`
+}
+
+// CommentThread корневой комментарий с ответами в хронологическом порядке
+type CommentThread struct {
+	Comment
+	Replies []Comment `json:"replies"`
+}
+
+// ChatMessageRequest запрос на отправку или правку сообщения чата
+type ChatMessageRequest struct {
+	Text string `json:"text"`
+}
+
+// CommentRequest запрос на создание комментария
+type CommentRequest struct {
+	ObjectID string `json:"object_id,omitempty"` // В REST берется из пути
+	ParentID int    `json:"parent_id,omitempty"` // Ответ в ветку
+	Text     string `json:"text"`
+}
+
+// MessageEdit payload WebSocket-сообщений chat_edit и comment_edit
+type MessageEdit struct {
+	ID   int    `json:"id"`
+	Text string `json:"text"`
+}
+
+// MessageDelete payload рассылки об удалении сообщения или комментария.
+// При удалении корня ветки вместе с ним удаляются ответы.
+type MessageDelete struct {
+	ID       int    `json:"id"`
+	ObjectID string `json:"object_id,omitempty"`
+	Replies  []int  `json:"replies,omitempty"`
+}
+
+// ChatPage страница сообщений или комментариев в хронологическом порядке
+type ChatPage struct {
+	Items   interface{} `json:"items"`
+	HasMore bool        `json:"has_more"`         // Есть более ранние записи
+	Before  int         `json:"before,omitempty"` // Курсор для следующей страницы
+}
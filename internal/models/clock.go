@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// ClockRequest запрос на управление часами сервера.
+// Поля применяются в порядке: frozen, time, advance.
+type ClockRequest struct {
+	Frozen  *bool      `json:"frozen,omitempty"`
+	Time    *time.Time `json:"time,omitempty"`
+	Advance string     `json:"advance,omitempty"` // длительность в формате Go, например "1h30m"
+}
+
+// ClockState текущее состояние часов сервера
+type ClockState struct {
+	Now    time.Time `json:"now"`
+	Frozen bool      `json:"frozen"`
+}
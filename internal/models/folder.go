@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// Ограничения тегов и папок
+const (
+	MaxBoardTags   = 20
+	MaxTagLength   = 32
+	MaxFolderName  = 100
+	MaxFolderDepth = 8 // Уровней вложенности, считая корневую папку
+)
+
+// Folder папка пользователя для досок. Папки у каждого пользователя свои,
+// одна и та же общая доска может лежать в разных папках у разных участников.
+type Folder struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"-"`
+	ParentID  int       `json:"parent_id"` // 0 - папка верхнего уровня
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// FolderCreateRequest запрос на создание папки
+type FolderCreateRequest struct {
+	Name     string `json:"name"`
+	ParentID int    `json:"parent_id"`
+}
+
+// FolderUpdateRequest запрос на переименование или перенос папки, пустые поля не меняются
+type FolderUpdateRequest struct {
+	Name     *string `json:"name"`
+	ParentID *int    `json:"parent_id"`
+}
+
+// BoardTagsRequest запрос на замену тегов доски
+type BoardTagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+// BoardFolderRequest запрос на перенос доски в папку, 0 - убрать из папки
+type BoardFolderRequest struct {
+	FolderID int `json:"folder_id"`
+}
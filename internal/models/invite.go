@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+)
+
+// Роли участников доски
+const (
+	RoleOwner  = "owner"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+// BoardInvite приглашение на доску по ссылке или на email
+type BoardInvite struct {
+	Token     string     `json:"token"`
+	BoardID   string     `json:"board_id"`
+	Role      string     `json:"role"`            // editor или viewer
+	Email     string     `json:"email,omitempty"` // Если указан, приглашение может принять только этот пользователь
+	CreatedBy int        `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	MaxUses   int        `json:"max_uses"` // 0 - без ограничений
+	Uses      int        `json:"uses"`
+}
+
+// Expired проверяет, истек ли срок приглашения к моменту now
+func (i *BoardInvite) Expired(now time.Time) bool {
+	return i.ExpiresAt != nil && !now.Before(*i.ExpiresAt)
+}
+
+// Exhausted проверяет, исчерпан ли лимит использований
+func (i *BoardInvite) Exhausted() bool {
+	return i.MaxUses > 0 && i.Uses >= i.MaxUses
+}
+
+// InviteCreateRequest запрос на создание приглашения
+type InviteCreateRequest struct {
+	Role      string `json:"role"`
+	Email     string `json:"email,omitempty"`
+	ExpiresIn string `json:"expires_in,omitempty"` // длительность в формате Go, например "72h"
+	MaxUses   int    `json:"max_uses"`
+}
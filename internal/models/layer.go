@@ -0,0 +1,68 @@
+package models
+
+// Ограничения слоев
+const (
+	MaxBoardLayers = 50
+	MaxLayerName   = 100
+)
+
+// Действия операции reorder
+const (
+	ReorderFront    = "front"    // Поверх всех объектов
+	ReorderBack     = "back"     // Под всеми объектами
+	ReorderForward  = "forward"  // На одну позицию выше
+	ReorderBackward = "backward" // На одну позицию ниже
+)
+
+// Layer именованный слой доски. Скрытие слоя - подсказка клиенту, сервер объекты не фильтрует.
+type Layer struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Visible bool   `json:"visible"`
+	Locked  bool   `json:"locked"` // Объекты слоя нельзя менять; ставит и снимает владелец доски
+}
+
+// LayerCreateRequest запрос на создание слоя, новый слой кладется наверх
+type LayerCreateRequest struct {
+	Name    string `json:"name"`
+	Visible *bool  `json:"visible"` // По умолчанию true
+}
+
+// LayerUpdateRequest частичное изменение слоя; position - новый индекс снизу, с 0
+type LayerUpdateRequest struct {
+	Name     *string `json:"name"`
+	Visible  *bool   `json:"visible"`
+	Locked   *bool   `json:"locked"`
+	Position *int    `json:"position"`
+}
+
+// ObjectsReorderRequest запрос на изменение порядка наложения объектов.
+// Объекты групп перемещаются вместе с группой.
+type ObjectsReorderRequest struct {
+	IDs    []string `json:"ids"`
+	Action string   `json:"action"` // front, back, forward, backward
+}
+
+// ObjectsReorder payload сообщения objects_reorder: новые z изменившихся объектов
+type ObjectsReorder struct {
+	Z map[string]int `json:"z"`
+}
+
+// ObjectsGroupRequest запрос на объединение объектов в группу
+type ObjectsGroupRequest struct {
+	ID       string   `json:"id,omitempty"` // ID группы, без него сервер выдает его сам
+	Children []string `json:"children"`
+}
+
+// ObjectLockRequest запрос на закрепление или открепление объекта
+type ObjectLockRequest struct {
+	ID     string `json:"id"`
+	Locked bool   `json:"locked"`
+}
+
+// LayerDelete payload сообщения layer_delete
+type LayerDelete struct {
+	ID     string        `json:"id"`
+	Layers []Layer       `json:"layers"`
+	Moved  []BoardObject `json:"moved"` // Объекты удаленного слоя, перенесенные вне слоев
+}
@@ -0,0 +1,114 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Размер страницы списков
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 200
+)
+
+// Сортировки списков досок
+const (
+	BoardSortLikes     = "likes"
+	BoardSortCreatedAt = "created_at"
+	BoardSortName      = "name"
+	BoardSortActivity  = "activity" // По времени последнего изменения доски
+)
+
+// Фильтр принадлежности доски
+const (
+	OwnershipOwned  = "owned"  // Доски пользователя
+	OwnershipShared = "shared" // Доски, к которым пользователю выдали доступ
+)
+
+// BoardQuery параметры списка досок: область, фильтры, сортировка и страница
+type BoardQuery struct {
+	UserID   int  // Пользователь, от имени которого строится список; 0 - аноним
+	Public   bool // Только публичные доски, иначе доски с доступом UserID
+	Template bool // Только шаблоны: публичные и с доступом UserID, независимо от Public
+
+	Ownership   string // owned, shared или пусто
+	Name        string // Подстрока названия без учета регистра
+	MinLikes    int
+	CreatedFrom *time.Time // Включительно
+	CreatedTo   *time.Time // Не включительно
+	Tag         string     // Доски с этим тегом
+	FolderID    *int       // Доски в папке UserID; 0 - доски вне папок
+	Favorite    bool       // Только избранные доски UserID
+
+	Sort  string
+	Desc  bool
+	Limit int
+
+	Offset int
+	After  *BoardCursor // Курсор: страница начинается после этой доски
+}
+
+// BoardList страница списка досок
+type BoardList struct {
+	Boards     []Board
+	Total      int // Досок, подходящих под фильтры, без учета страницы
+	HasMore    bool
+	NextCursor string
+}
+
+// ListMeta метаданные постраничного списка
+type ListMeta struct {
+	Total      int    `json:"total"`
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// BoardCursor позиция в списке досок: ключи сортировки последней доски страницы.
+// Курсор привязан к сортировке, с которой получен.
+type BoardCursor struct {
+	Sort      string    `json:"s"`
+	Desc      bool      `json:"d,omitempty"`
+	ID        string    `json:"id"`
+	Name      string    `json:"n,omitempty"`
+	Likes     int       `json:"l,omitempty"`
+	CreatedAt time.Time `json:"c"`
+	UpdatedAt time.Time `json:"u"`
+}
+
+// NewBoardCursor создает курсор, указывающий на доску
+func NewBoardCursor(board Board, sort string, desc bool) BoardCursor {
+	return BoardCursor{
+		Sort:      sort,
+		Desc:      desc,
+		ID:        board.ID,
+		Name:      board.Name,
+		Likes:     board.Likes,
+		CreatedAt: board.CreatedAt,
+		UpdatedAt: board.UpdatedAt,
+	}
+}
+
+// Encode кодирует курсор в непрозрачную строку для клиента
+func (c BoardCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Board возвращает доску с ключами сортировки курсора, чтобы сравнивать ее с другими
+func (c BoardCursor) Board() Board {
+	return Board{ID: c.ID, Name: c.Name, Likes: c.Likes, CreatedAt: c.CreatedAt, UpdatedAt: c.UpdatedAt}
+}
+
+// DecodeBoardCursor разбирает курсор, полученный клиентом
+func DecodeBoardCursor(value string) (BoardCursor, error) {
+	var cursor BoardCursor
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || json.Unmarshal(data, &cursor) != nil || cursor.ID == "" {
+		return cursor, errors.New("invalid cursor")
+	}
+	return cursor, nil
+}
@@ -0,0 +1,28 @@
+package models
+
+// HubMetrics счетчики WebSocket Hub
+type HubMetrics struct {
+	ActiveConnections    int   `json:"active_connections"`
+	ActiveSpectators     int   `json:"active_spectators"`
+	ActiveBoards         int   `json:"active_boards"`
+	ActiveInboxes        int   `json:"active_inboxes"` // Пользователи, подключенные к каналу уведомлений
+	ConnectionsTotal     int64 `json:"connections_total"`
+	MessagesReceived     int64 `json:"messages_received"`
+	MessagesBroadcast    int64 `json:"messages_broadcast"`
+	DroppedSlowConsumers int64 `json:"dropped_slow_consumers"` // Отключены из-за переполненной очереди
+	OversizedMessages    int64 `json:"oversized_messages"`     // Отключены за превышение MaxMessageSize
+	PongTimeouts         int64 `json:"pong_timeouts"`          // Отключены по таймауту чтения
+	ServerDisconnects    int64 `json:"server_disconnects"`     // Отключены из-за потери прав
+
+	// Форматы сообщений: объем до сжатия permessage-deflate
+	ActiveMsgpack    int   `json:"active_msgpack"` // Подключения с подпротоколом board.msgpack
+	BytesSentJSON    int64 `json:"bytes_sent_json"`
+	BytesSentMsgpack int64 `json:"bytes_sent_msgpack"`
+
+	// Брокер между экземплярами сервера
+	Origin            string `json:"origin"`             // ID этого экземпляра
+	MessagesPublished int64  `json:"messages_published"` // Отправлено в брокер
+	PublishDropped    int64  `json:"publish_dropped"`    // Отброшено из-за переполненной очереди публикации
+	MessagesRelayed   int64  `json:"messages_relayed"`   // Получено от других экземпляров
+	BrokerErrors      int64  `json:"broker_errors"`
+}
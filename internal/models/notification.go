@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+)
+
+// Типы уведомлений
+const (
+	NotificationMention     = "mention"      // Упоминание в чате или комментарии
+	NotificationBoardShared = "board_shared" // Владелец выдал доступ к доске
+	NotificationBoardLiked  = "board_liked"  // Лайк доске пользователя
+)
+
+// Notification уведомление пользователя
+type Notification struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	Type      string    `json:"type"`
+	BoardID   string    `json:"board_id"`
+	BoardName string    `json:"board_name"`
+	ActorID   int       `json:"actor_id"` // Кто вызвал уведомление
+	ActorName string    `json:"actor_name"`
+	ObjectID  string    `json:"object_id,omitempty"` // Для упоминаний в комментариях
+	MessageID int       `json:"message_id,omitempty"`
+	Role      string    `json:"role,omitempty"` // Выданная роль для board_shared
+	Text      string    `json:"text,omitempty"`
+	Read      bool      `json:"read"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NotificationPage страница уведомлений от новых к старым
+type NotificationPage struct {
+	Items   []Notification `json:"items"`
+	Unread  int            `json:"unread"`           // Всего непрочитанных
+	HasMore bool           `json:"has_more"`         // Есть более старые уведомления
+	Before  int            `json:"before,omitempty"` // Курсор для следующей страницы
+}
+
+// NotificationsReadRequest запрос на отметку уведомлений прочитанными
+type NotificationsReadRequest struct {
+	IDs []int `json:"ids"` // Нет поля - все уведомления, [] - ни одного
+}
+
+// NotificationPush payload сообщения notification в канале уведомлений
+type NotificationPush struct {
+	Notification Notification `json:"notification"`
+	Unread       int          `json:"unread"`
+}
+
+// NotificationsRead payload сообщения notifications_read: отметка прочитанными
+// в одной вкладке рассылается во все подключения пользователя
+type NotificationsRead struct {
+	IDs    []int `json:"ids"`
+	Unread int   `json:"unread"`
+}
@@ -0,0 +1,18 @@
+package models
+
+// Типы результатов поиска
+const (
+	SearchResultBoard  = "board"  // Совпадение в названии доски
+	SearchResultObject = "object" // Совпадение в тексте объекта
+)
+
+// SearchResult найденная доска или объект доски
+type SearchResult struct {
+	Type       string  `json:"type"` // board или object
+	BoardID    string  `json:"board_id"`
+	BoardName  string  `json:"board_name"`
+	ObjectID   string  `json:"object_id,omitempty"`
+	ObjectType string  `json:"object_type,omitempty"`
+	Score      float64 `json:"score"`
+	Snippet    string  `json:"snippet"` // HTML: текст экранирован, совпадения обернуты в <mark>
+}
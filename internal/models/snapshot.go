@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// MaxSnapshotLabel максимальная длина названия снимка
+const MaxSnapshotLabel = 100
+
+// SnapshotLive имя текущего состояния доски в запросе сравнения
+const SnapshotLive = "live"
+
+// Snapshot именованный снимок объектов доски
+type Snapshot struct {
+	ID          int                    `json:"id"`
+	BoardID     string                 `json:"board_id"`
+	Label       string                 `json:"label"`
+	AuthorID    int                    `json:"author_id"`
+	AuthorName  string                 `json:"author_name"`
+	ObjectCount int                    `json:"object_count"`
+	Objects     map[string]BoardObject `json:"objects,omitempty"` // Только в ответе на запрос одного снимка
+	CreatedAt   time.Time              `json:"created_at"`
+}
+
+// SnapshotRequest запрос на создание снимка
+type SnapshotRequest struct {
+	Label string `json:"label"`
+}
+
+// ObjectChange объект, измененный между двумя состояниями доски
+type ObjectChange struct {
+	ID     string      `json:"id"`
+	Fields []string    `json:"fields"` // Измененные поля в JSON-именах
+	Before BoardObject `json:"before"`
+	After  BoardObject `json:"after"`
+}
+
+// SnapshotDiff различия между двумя снимками или снимком и текущей доской
+type SnapshotDiff struct {
+	From    string         `json:"from"` // ID снимка или live
+	To      string         `json:"to"`
+	Added   []BoardObject  `json:"added"`
+	Removed []BoardObject  `json:"removed"`
+	Changed []ObjectChange `json:"changed"`
+}
+
+// BoardReset сообщение о замене всех объектов доски при восстановлении снимка
+type BoardReset struct {
+	SnapshotID int           `json:"snapshot_id"`
+	Label      string        `json:"label"`
+	RestoredBy int           `json:"restored_by"`
+	Objects    []BoardObject `json:"objects"`
+}
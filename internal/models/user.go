@@ -11,7 +11,14 @@ type User struct {
 	Email     string    `json:"email"`
 	Password  string    `json:"-"` // Не отдаем пароль в JSON
 	Token     string    `json:"-"`
-	CreatedAt time.Time `json:"-"`
+	// TokenExpiresAt момент истечения токена, нулевое значение - без срока
+	TokenExpiresAt time.Time `json:"-"`
+	CreatedAt      time.Time `json:"-"`
+}
+
+// TokenExpired проверяет, истек ли токен к моменту now
+func (u *User) TokenExpired(now time.Time) bool {
+	return !u.TokenExpiresAt.IsZero() && !now.Before(u.TokenExpiresAt)
 }
 
 // RegistrationRequest структура запроса регистрации
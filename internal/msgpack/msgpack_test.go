@@ -0,0 +1,181 @@
+package msgpack
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMarshal(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string // hex
+	}{
+		{"nil", nil, "c0"},
+		{"false", false, "c2"},
+		{"true", true, "c3"},
+		{"zero", 0, "00"},
+		{"positive fixint", 127, "7f"},
+		{"negative fixint", -32, "e0"},
+		{"uint8", 255, "ccff"},
+		{"uint16", 256, "cd0100"},
+		{"uint32", 65536, "ce00010000"},
+		{"uint64", int64(1) << 32, "cf0000000100000000"},
+		{"int8", -33, "d0df"},
+		{"int16", -129, "d1ff7f"},
+		{"int32", -32769, "d2ffff7fff"},
+		{"int64", int64(math.MinInt32) - 1, "d3ffffffff7fffffff"},
+		{"uint64 max", uint64(math.MaxUint64), "cfffffffffffffffff"},
+		{"integral float", 2.0, "02"},
+		{"float", 1.5, "cb3ff8000000000000"},
+		{"json integer", json.Number("3"), "03"},
+		{"json big integer", json.Number("18446744073709551615"), "cfffffffffffffffff"},
+		{"json float", json.Number("0.5"), "cb3fe0000000000000"},
+		{"empty string", "", "a0"},
+		{"fixstr", "abc", "a3616263"},
+		{"str8", strings.Repeat("a", 32), "d920" + strings.Repeat("61", 32)},
+		{"bin", []byte{1, 2}, "c4020102"},
+		{"empty array", []interface{}{}, "90"},
+		{"fixarray", []interface{}{1, "a"}, "9201a161"},
+		{"array16", make([]interface{}, 16), "dc0010" + strings.Repeat("c0", 16)},
+		{"map with sorted keys", map[string]interface{}{"b": 1, "a": true}, "82a161c3a16201"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Marshal(tt.value)
+			if err != nil {
+				t.Fatalf("Marshal(%v): %v", tt.value, err)
+			}
+			if hex.EncodeToString(got) != tt.want {
+				t.Errorf("Marshal(%v) = %x, want %s", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+	}{
+		{"unsupported type", struct{}{}},
+		{"unsupported item", []interface{}{1, struct{}{}}},
+		{"invalid number", json.Number("1e")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Marshal(tt.value); err == nil {
+				t.Errorf("Marshal(%v) succeeded, want error", tt.value)
+			}
+		})
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	tests := []struct {
+		name string
+		data string // hex
+		want interface{}
+	}{
+		{"nil", "c0", nil},
+		{"bool", "c3", true},
+		{"positive fixint", "7f", int64(127)},
+		{"negative fixint", "ff", int64(-1)},
+		{"uint8", "ccff", int64(255)},
+		{"uint16", "cd0100", int64(256)},
+		{"uint64 above int64", "cfffffffffffffffff", uint64(math.MaxUint64)},
+		{"int8", "d080", int64(-128)},
+		{"int16", "d1ff7f", int64(-129)},
+		{"int32", "d2ffff7fff", int64(-32769)},
+		{"int64", "d3ffffffff7fffffff", int64(math.MinInt32) - 1},
+		{"float32", "ca3fc00000", 1.5},
+		{"float64", "cb3ff8000000000000", 1.5},
+		{"fixstr", "a3616263", "abc"},
+		{"str8", "d903616263", "abc"},
+		{"empty bin", "c400", []byte{}},
+		{"bin", "c4020102", []byte{1, 2}},
+		{"empty array", "90", []interface{}{}},
+		{"array16", "dc000201c0", []interface{}{int64(1), nil}},
+		{"empty map", "80", map[string]interface{}{}},
+		{"nested map", "81a16191a162", map[string]interface{}{"a": []interface{}{"b"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, _ := hex.DecodeString(tt.data)
+			got, err := Unmarshal(data)
+			if err != nil {
+				t.Fatalf("Unmarshal(%s): %v", tt.data, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Unmarshal(%s) = %#v, want %#v", tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string // hex
+		want error  // nil - подходит любая ошибка
+	}{
+		{"empty", "", ErrTruncated},
+		{"truncated string", "a361", ErrTruncated},
+		{"truncated array", "9201", ErrTruncated},
+		{"truncated uint16", "cd01", ErrTruncated},
+		{"array longer than data", "ddffffffff", ErrTruncated},
+		{"bin longer than data", "c6ffffffff", ErrTruncated},
+		{"trailing data", "0102", nil},
+		{"non-string key", "810102", nil},
+		{"unsupported type", "c1", nil},
+		{"nesting too deep", strings.Repeat("91", maxDepth+2) + "c0", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, _ := hex.DecodeString(tt.data)
+			_, err := Unmarshal(data)
+			if err == nil {
+				t.Fatalf("Unmarshal(%s) succeeded, want error", tt.data)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("Unmarshal(%s) error = %v, want %v", tt.data, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	tests := []string{
+		`null`,
+		`[]`,
+		`{}`,
+		`""`,
+		`{"ids":[]}`,
+		`{"a":[1,-2,1.5,"x",null,true],"b":{"c":18446744073709551615}}`,
+		`{"payload":{"x":10.25,"y":-300000},"type":"cursor"}`,
+	}
+
+	for _, input := range tests {
+		packed, err := FromJSON([]byte(input))
+		if err != nil {
+			t.Fatalf("FromJSON(%s): %v", input, err)
+		}
+		got, err := ToJSON(packed)
+		if err != nil {
+			t.Fatalf("ToJSON(FromJSON(%s)): %v", input, err)
+		}
+		if !bytes.Equal(got, []byte(input)) {
+			t.Errorf("round trip of %s = %s", input, got)
+		}
+	}
+}
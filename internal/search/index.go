@@ -0,0 +1,350 @@
+// Package search ведет обратный индекс названий досок и текстовых объектов
+// и ищет по нему с ранжированием, поиском по префиксу и подсветкой совпадений.
+package search
+
+import (
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Параметры ранжирования BM25
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// Веса совпадений
+const (
+	nameBoost    = 2.0 // Совпадение в названии доски важнее совпадения в тексте
+	prefixWeight = 0.5 // Совпадение по префиксу слабее точного
+)
+
+// MaxQueryTerms сколько слов запроса учитывается, остальные отбрасываются
+const MaxQueryTerms = 10
+
+// snippetRadius сколько символов текста показывается вокруг первого совпадения
+const snippetRadius = 60
+
+// Key документ индекса: название доски (ObjectID пустой) или текстовый объект
+type Key struct {
+	BoardID  string
+	ObjectID string
+}
+
+// document проиндексированный текст
+type document struct {
+	text  string
+	terms map[string]int // Слово -> число вхождений
+	size  int            // Всего слов
+}
+
+// Hit найденный документ
+type Hit struct {
+	Key
+	Score   float64
+	Snippet string // Фрагмент текста в HTML: текст экранирован, совпадения в <mark>
+}
+
+// Index обратный индекс. Безопасен для одновременного использования.
+type Index struct {
+	mu        sync.RWMutex
+	docs      map[Key]*document
+	postings  map[string]map[Key]int // Слово -> документы и число вхождений
+	terms     []string               // Все слова по возрастанию, для поиска по префиксу
+	totalSize int                    // Сумма длин документов, для средней длины в BM25
+}
+
+// NewIndex создает пустой индекс
+func NewIndex() *Index {
+	return &Index{
+		docs:     make(map[Key]*document),
+		postings: make(map[string]map[Key]int),
+	}
+}
+
+// Put индексирует текст документа, заменяя прежний. Пустой текст удаляет документ.
+func (idx *Index) Put(key Key, text string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if doc, ok := idx.docs[key]; ok && doc.text == text {
+		return
+	}
+	idx.removeLocked(key)
+
+	tokens := Tokenize(text)
+	if len(tokens) == 0 {
+		return
+	}
+
+	doc := &document{text: text, terms: make(map[string]int), size: len(tokens)}
+	for _, token := range tokens {
+		doc.terms[token]++
+	}
+	for term, count := range doc.terms {
+		postings := idx.postings[term]
+		if postings == nil {
+			postings = make(map[Key]int)
+			idx.postings[term] = postings
+			idx.insertTermLocked(term)
+		}
+		postings[key] = count
+	}
+	idx.docs[key] = doc
+	idx.totalSize += doc.size
+}
+
+// Remove удаляет документ из индекса
+func (idx *Index) Remove(key Key) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.removeLocked(key)
+}
+
+// RemoveObjects удаляет из индекса все объекты доски, оставляя ее название
+func (idx *Index) RemoveObjects(boardID string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for key := range idx.docs {
+		if key.BoardID == boardID && key.ObjectID != "" {
+			idx.removeLocked(key)
+		}
+	}
+}
+
+// Search ищет документы, содержащие все слова запроса. Слово запроса совпадает
+// и с более длинными словами, начинающимися с него, но с меньшим весом. allow отбирает
+// доски, доступные вызывающему. Результаты отсортированы по убыванию релевантности.
+func (idx *Index) Search(query string, allow func(boardID string) bool) []Hit {
+	queryTerms := Tokenize(query)
+	if len(queryTerms) > MaxQueryTerms {
+		queryTerms = queryTerms[:MaxQueryTerms]
+	}
+	if len(queryTerms) == 0 {
+		return []Hit{}
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	scores := make(map[Key]float64)
+	matched := make(map[Key]int) // Сколько слов запроса нашлось в документе
+	for _, queryTerm := range queryTerms {
+		best := make(map[Key]float64)
+		for _, term := range idx.expandLocked(queryTerm) {
+			weight := 1.0
+			if term != queryTerm {
+				weight = prefixWeight
+			}
+			idf := idx.idfLocked(term)
+			for key, count := range idx.postings[term] {
+				score := weight * idf * idx.tfLocked(key, count)
+				if score > best[key] {
+					best[key] = score
+				}
+			}
+		}
+		for key, score := range best {
+			scores[key] += score
+			matched[key]++
+		}
+	}
+
+	allowed := make(map[string]bool)
+	hits := []Hit{}
+	for key, score := range scores {
+		if matched[key] < len(queryTerms) {
+			continue
+		}
+		ok, seen := allowed[key.BoardID]
+		if !seen {
+			ok = allow(key.BoardID)
+			allowed[key.BoardID] = ok
+		}
+		if !ok {
+			continue
+		}
+		if key.ObjectID == "" {
+			score *= nameBoost
+		}
+		hits = append(hits, Hit{
+			Key:     key,
+			Score:   math.Round(score*1000) / 1000,
+			Snippet: snippet(idx.docs[key].text, queryTerms),
+		})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		if hits[i].BoardID != hits[j].BoardID {
+			return hits[i].BoardID < hits[j].BoardID
+		}
+		return hits[i].ObjectID < hits[j].ObjectID
+	})
+	return hits
+}
+
+// removeLocked удаляет документ, вызывающий держит idx.mu на запись
+func (idx *Index) removeLocked(key Key) {
+	doc, ok := idx.docs[key]
+	if !ok {
+		return
+	}
+
+	for term := range doc.terms {
+		postings := idx.postings[term]
+		delete(postings, key)
+		if len(postings) == 0 {
+			delete(idx.postings, term)
+			idx.deleteTermLocked(term)
+		}
+	}
+	delete(idx.docs, key)
+	idx.totalSize -= doc.size
+}
+
+// insertTermLocked добавляет новое слово в отсортированный список
+func (idx *Index) insertTermLocked(term string) {
+	i := sort.SearchStrings(idx.terms, term)
+	idx.terms = append(idx.terms, "")
+	copy(idx.terms[i+1:], idx.terms[i:])
+	idx.terms[i] = term
+}
+
+// deleteTermLocked убирает слово из отсортированного списка
+func (idx *Index) deleteTermLocked(term string) {
+	i := sort.SearchStrings(idx.terms, term)
+	if i < len(idx.terms) && idx.terms[i] == term {
+		idx.terms = append(idx.terms[:i], idx.terms[i+1:]...)
+	}
+}
+
+// expandLocked возвращает слова индекса, начинающиеся с prefix, включая его самого
+func (idx *Index) expandLocked(prefix string) []string {
+	var terms []string
+	for i := sort.SearchStrings(idx.terms, prefix); i < len(idx.terms); i++ {
+		if !strings.HasPrefix(idx.terms[i], prefix) {
+			break
+		}
+		terms = append(terms, idx.terms[i])
+	}
+	return terms
+}
+
+// idfLocked обратная частота документов слова
+func (idx *Index) idfLocked(term string) float64 {
+	n := float64(len(idx.docs))
+	df := float64(len(idx.postings[term]))
+	return math.Log(1 + (n-df+0.5)/(df+0.5))
+}
+
+// tfLocked нормированная по длине документа частота слова (BM25)
+func (idx *Index) tfLocked(key Key, count int) float64 {
+	avg := float64(idx.totalSize) / float64(len(idx.docs))
+	size := float64(idx.docs[key].size)
+	tf := float64(count)
+	return tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*size/avg))
+}
+
+// Tokenize разбивает текст на слова в нижнем регистре: последовательности букв и цифр
+func Tokenize(text string) []string {
+	var tokens []string
+	for _, span := range wordSpans(text) {
+		tokens = append(tokens, strings.ToLower(text[span[0]:span[1]]))
+	}
+	return tokens
+}
+
+// wordSpans возвращает байтовые границы слов текста
+func wordSpans(text string) [][2]int {
+	var spans [][2]int
+	start := -1
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			spans = append(spans, [2]int{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, [2]int{start, len(text)})
+	}
+	return spans
+}
+
+// snippet вырезает фрагмент вокруг первого совпадения и подсвечивает слова,
+// начинающиеся с какого-либо слова запроса
+func snippet(text string, queryTerms []string) string {
+	var marks [][2]int
+	for _, span := range wordSpans(text) {
+		word := strings.ToLower(text[span[0]:span[1]])
+		for _, term := range queryTerms {
+			if strings.HasPrefix(word, term) {
+				marks = append(marks, span)
+				break
+			}
+		}
+	}
+
+	// Окно вокруг первого совпадения, границы выравниваются по символам
+	from, to := 0, len(text)
+	if len(marks) > 0 {
+		from = backRunes(text, marks[0][0], snippetRadius)
+		to = forwardRunes(text, marks[0][1], snippetRadius*2)
+	} else {
+		to = forwardRunes(text, 0, snippetRadius*2)
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	pos := from
+	for _, mark := range marks {
+		if mark[0] < from || mark[1] > to {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:mark[0]]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[mark[0]:mark[1]]))
+		b.WriteString("</mark>")
+		pos = mark[1]
+	}
+	b.WriteString(html.EscapeString(text[pos:to]))
+	if to < len(text) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// backRunes отступает от байтовой позиции pos на n символов назад
+func backRunes(text string, pos int, n int) int {
+	for ; n > 0 && pos > 0; n-- {
+		_, size := utf8.DecodeLastRuneInString(text[:pos])
+		pos -= size
+	}
+	return pos
+}
+
+// forwardRunes продвигается от байтовой позиции pos на n символов вперед
+func forwardRunes(text string, pos int, n int) int {
+	for ; n > 0 && pos < len(text); n-- {
+		_, size := utf8.DecodeRuneInString(text[pos:])
+		pos += size
+	}
+	return pos
+}
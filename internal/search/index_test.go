@@ -0,0 +1,195 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func allowAll(string) bool { return true }
+
+// keys возвращает ключи результатов в порядке выдачи
+func keys(hits []Hit) []Key {
+	result := make([]Key, len(hits))
+	for i, hit := range hits {
+		result[i] = hit.Key
+	}
+	return result
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"", nil},
+		{"  ,.!  ", nil},
+		{"Hello, World!", []string{"hello", "world"}},
+		{"План Q3: запуск-2024", []string{"план", "q3", "запуск", "2024"}},
+		{"e-mail", []string{"e", "mail"}},
+	}
+
+	for _, tt := range tests {
+		if got := Tokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSearchScore(t *testing.T) {
+	idx := NewIndex()
+	idx.Put(Key{BoardID: "b1", ObjectID: "o1"}, "roadmap")
+
+	hits := idx.Search("roadmap", allowAll)
+	if len(hits) != 1 {
+		t.Fatalf("Search returned %d hits, want 1", len(hits))
+	}
+	// Один документ: idf = ln(1 + 0.5/1.5), tf при средней длине равен 1
+	if hits[0].Score != 0.288 {
+		t.Errorf("Score = %v, want 0.288", hits[0].Score)
+	}
+}
+
+func TestSearchRanking(t *testing.T) {
+	tests := []struct {
+		name  string
+		docs  map[Key]string
+		query string
+		want  []Key
+	}{
+		{
+			name: "name boosted over text",
+			docs: map[Key]string{
+				{BoardID: "b1", ObjectID: "o1"}: "roadmap",
+				{BoardID: "b2"}:                 "roadmap",
+			},
+			query: "roadmap",
+			want:  []Key{{BoardID: "b2"}, {BoardID: "b1", ObjectID: "o1"}},
+		},
+		{
+			name: "shorter document ranks higher",
+			docs: map[Key]string{
+				{BoardID: "b1", ObjectID: "long"}:  "roadmap for the next quarter with many details",
+				{BoardID: "b1", ObjectID: "short"}: "roadmap draft",
+			},
+			query: "roadmap",
+			want:  []Key{{BoardID: "b1", ObjectID: "short"}, {BoardID: "b1", ObjectID: "long"}},
+		},
+		{
+			name: "more occurrences rank higher",
+			docs: map[Key]string{
+				{BoardID: "b1", ObjectID: "once"}:  "sprint review notes",
+				{BoardID: "b1", ObjectID: "twice"}: "sprint sprint notes",
+			},
+			query: "sprint",
+			want:  []Key{{BoardID: "b1", ObjectID: "twice"}, {BoardID: "b1", ObjectID: "once"}},
+		},
+		{
+			name: "exact match ranks above prefix match",
+			docs: map[Key]string{
+				{BoardID: "b1", ObjectID: "prefix"}: "designer",
+				{BoardID: "b1", ObjectID: "exact"}:  "design",
+			},
+			query: "design",
+			want:  []Key{{BoardID: "b1", ObjectID: "exact"}, {BoardID: "b1", ObjectID: "prefix"}},
+		},
+		{
+			name: "all query terms required",
+			docs: map[Key]string{
+				{BoardID: "b1", ObjectID: "both"}: "team retro",
+				{BoardID: "b1", ObjectID: "one"}:  "team lunch",
+			},
+			query: "team retro",
+			want:  []Key{{BoardID: "b1", ObjectID: "both"}},
+		},
+		{
+			name: "equal scores ordered by key",
+			docs: map[Key]string{
+				{BoardID: "b2", ObjectID: "o1"}: "idea",
+				{BoardID: "b1", ObjectID: "o2"}: "idea",
+				{BoardID: "b1", ObjectID: "o1"}: "idea",
+			},
+			query: "IDEA",
+			want:  []Key{{BoardID: "b1", ObjectID: "o1"}, {BoardID: "b1", ObjectID: "o2"}, {BoardID: "b2", ObjectID: "o1"}},
+		},
+		{
+			name:  "no terms in query",
+			docs:  map[Key]string{{BoardID: "b1"}: "board"},
+			query: "!!",
+			want:  []Key{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx := NewIndex()
+			for key, text := range tt.docs {
+				idx.Put(key, text)
+			}
+			if got := keys(idx.Search(tt.query, allowAll)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchAllow(t *testing.T) {
+	idx := NewIndex()
+	idx.Put(Key{BoardID: "mine"}, "budget")
+	idx.Put(Key{BoardID: "foreign"}, "budget")
+
+	got := keys(idx.Search("budget", func(boardID string) bool { return boardID == "mine" }))
+	if want := []Key{{BoardID: "mine"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Search = %v, want %v", got, want)
+	}
+}
+
+func TestIndexUpdates(t *testing.T) {
+	idx := NewIndex()
+	name := Key{BoardID: "b1"}
+	object := Key{BoardID: "b1", ObjectID: "o1"}
+	idx.Put(name, "launch plan")
+	idx.Put(object, "launch checklist")
+
+	// Замена текста убирает старые слова
+	idx.Put(object, "release checklist")
+	if got := keys(idx.Search("launch", allowAll)); !reflect.DeepEqual(got, []Key{name}) {
+		t.Errorf("after Put Search(launch) = %v, want %v", got, []Key{name})
+	}
+
+	idx.RemoveObjects("b1")
+	if got := idx.Search("checklist", allowAll); len(got) != 0 {
+		t.Errorf("after RemoveObjects Search(checklist) = %v, want none", keys(got))
+	}
+
+	// Пустой текст удаляет документ
+	idx.Put(name, "")
+	if got := idx.Search("plan", allowAll); len(got) != 0 {
+		t.Errorf("after empty Put Search(plan) = %v, want none", keys(got))
+	}
+	if len(idx.docs) != 0 || len(idx.postings) != 0 || len(idx.terms) != 0 || idx.totalSize != 0 {
+		t.Errorf("index not empty: %d docs, %d postings, %d terms, size %d",
+			len(idx.docs), len(idx.postings), len(idx.terms), idx.totalSize)
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		terms []string
+		want  string
+	}{
+		{"highlight", "Plan the launch", []string{"launch"}, "Plan the <mark>launch</mark>"},
+		{"prefix highlight", "Launching soon", []string{"launch"}, "<mark>Launching</mark> soon"},
+		{"escaped", "<b>tom & jerry</b>", []string{"jerry"}, "&lt;b&gt;tom &amp; <mark>jerry</mark>&lt;/b&gt;"},
+		{"no match", "plain text", []string{"other"}, "plain text"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := snippet(tt.text, tt.terms); got != tt.want {
+				t.Errorf("snippet(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
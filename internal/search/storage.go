@@ -0,0 +1,138 @@
+package search
+
+import (
+	"sync"
+
+	"github.com/alexl/go-fake-api/internal/models"
+	"github.com/alexl/go-fake-api/internal/storage"
+)
+
+// IndexedStorage хранилище, которое поддерживает индекс в актуальном состоянии:
+// изменения досок и объектов проходят через него и сразу попадают в индекс
+type IndexedStorage struct {
+	storage.Storage
+	Index *Index
+
+	// Изменение хранилища и индекса выполняется целиком, иначе два одновременных
+	// изменения одного объекта могли бы попасть в индекс в обратном порядке
+	mu sync.Mutex
+}
+
+// NewIndexedStorage оборачивает хранилище. Индекс строится по мере изменений,
+// поэтому оборачивать нужно пустое хранилище, до создания первых досок.
+func NewIndexedStorage(s storage.Storage) *IndexedStorage {
+	return &IndexedStorage{Storage: s, Index: NewIndex()}
+}
+
+// CreateBoard создает доску и индексирует ее название и объекты
+func (is *IndexedStorage) CreateBoard(board *models.Board) error {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+
+	if err := is.Storage.CreateBoard(board); err != nil {
+		return err
+	}
+	is.indexBoard(board)
+	return nil
+}
+
+// UpdateBoardObject сохраняет объект и переиндексирует его текст
+func (is *IndexedStorage) UpdateBoardObject(boardID string, obj models.BoardObject) error {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+
+	if err := is.Storage.UpdateBoardObject(boardID, obj); err != nil {
+		return err
+	}
+	is.indexObject(boardID, obj)
+	return nil
+}
+
+// DeleteBoardObject удаляет объект и его текст из индекса
+func (is *IndexedStorage) DeleteBoardObject(boardID string, objectID string) error {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+
+	if err := is.Storage.DeleteBoardObject(boardID, objectID); err != nil {
+		return err
+	}
+	is.Index.Remove(Key{BoardID: boardID, ObjectID: objectID})
+	return nil
+}
+
+// ApplyObjectsBatch применяет пакет и переиндексирует затронутые объекты
+func (is *IndexedStorage) ApplyObjectsBatch(boardID string, userID int, updates []models.BoardObject, deletes []string) (models.ObjectsBatch, error) {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+
+	applied, err := is.Storage.ApplyObjectsBatch(boardID, userID, updates, deletes)
+	if err != nil {
+		return applied, err
+	}
+	for _, obj := range applied.Updates {
+		is.indexObject(boardID, obj)
+	}
+	for _, id := range applied.Deletes {
+		is.Index.Remove(Key{BoardID: boardID, ObjectID: id})
+	}
+	return applied, nil
+}
+
+// CreateBoardObject добавляет объект и индексирует затронутые объекты
+func (is *IndexedStorage) CreateBoardObject(boardID string, userID int, obj models.BoardObject) (models.ObjectsBatch, error) {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+
+	applied, err := is.Storage.CreateBoardObject(boardID, userID, obj)
+	if err != nil {
+		return applied, err
+	}
+	for _, obj := range applied.Updates {
+		is.indexObject(boardID, obj)
+	}
+	return applied, nil
+}
+
+// RestoreSnapshot заменяет объекты доски объектами снимка и переиндексирует доску
+func (is *IndexedStorage) RestoreSnapshot(boardID string, id int) ([]models.BoardObject, error) {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+
+	restored, err := is.Storage.RestoreSnapshot(boardID, id)
+	if err != nil {
+		return nil, err
+	}
+	is.Index.RemoveObjects(boardID)
+	for _, obj := range restored {
+		is.indexObject(boardID, obj)
+	}
+	return restored, nil
+}
+
+// indexBoard индексирует название доски и все ее текстовые объекты
+func (is *IndexedStorage) indexBoard(board *models.Board) {
+	is.Index.Put(Key{BoardID: board.ID}, board.Name)
+	for _, obj := range board.Objects {
+		is.indexObject(board.ID, obj)
+	}
+}
+
+// indexObject индексирует текст объекта или убирает объект из индекса,
+// если текста у него больше нет
+func (is *IndexedStorage) indexObject(boardID string, obj models.BoardObject) {
+	key := Key{BoardID: boardID, ObjectID: obj.ID}
+	if text := searchableText(obj); text != "" {
+		is.Index.Put(key, text)
+	} else {
+		is.Index.Remove(key)
+	}
+}
+
+// searchableText текст объекта для поиска. У изображений в Content лежит URL,
+// поэтому индексируются только текстовые объекты.
+func searchableText(obj models.BoardObject) string {
+	if obj.Type != models.ObjectText {
+		return ""
+	}
+	return obj.Content
+}
@@ -0,0 +1,265 @@
+package storage
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/alexl/go-fake-api/internal/models"
+)
+
+// ReorderObjects меняет порядок наложения объектов и перенумеровывает z всех объектов
+// доски подряд с 1. Объекты групп перемещаются вместе с группой.
+// Возвращает новые z изменившихся объектов.
+func (s *MemoryStorage) ReorderObjects(boardID string, userID int, ids []string, action string) (map[string]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	board, ok := s.boards[boardID]
+	if !ok {
+		return nil, ErrBoardNotFound
+	}
+
+	selected := make(map[string]bool)
+	for _, id := range ids {
+		obj, ok := board.Objects[id]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, id)
+		}
+		if err := checkEditable(board, obj, userID); err != nil {
+			return nil, err
+		}
+		selected[id] = true
+		for _, child := range descendantsLocked(board, id) {
+			selected[child] = true
+		}
+	}
+
+	order := make([]models.BoardObject, 0, len(board.Objects))
+	for _, obj := range board.Objects {
+		order = append(order, obj)
+	}
+	sortByZ(order)
+
+	switch action {
+	case models.ReorderFront, models.ReorderBack:
+		var moved, rest []models.BoardObject
+		for _, obj := range order {
+			if selected[obj.ID] {
+				moved = append(moved, obj)
+			} else {
+				rest = append(rest, obj)
+			}
+		}
+		if action == models.ReorderFront {
+			order = append(rest, moved...)
+		} else {
+			order = append(moved, rest...)
+		}
+	case models.ReorderForward:
+		// Идем сверху, чтобы соседние выбранные объекты не перепрыгивали друг через друга
+		for i := len(order) - 2; i >= 0; i-- {
+			if selected[order[i].ID] && !selected[order[i+1].ID] {
+				order[i], order[i+1] = order[i+1], order[i]
+			}
+		}
+	case models.ReorderBackward:
+		for i := 1; i < len(order); i++ {
+			if selected[order[i].ID] && !selected[order[i-1].ID] {
+				order[i], order[i-1] = order[i-1], order[i]
+			}
+		}
+	}
+
+	changed := make(map[string]int)
+	for i, obj := range order {
+		if obj.Z != i+1 {
+			obj.Z = i + 1
+			board.Objects[obj.ID] = obj
+			changed[obj.ID] = obj.Z
+		}
+	}
+	return changed, nil
+}
+
+// GroupObjects объединяет объекты в новую группу. Группа получает рамку вокруг объектов,
+// z верхнего из них и слой первого. Объект может входить только в одну группу,
+// группы можно вкладывать друг в друга. Возвращает группу и ее объекты.
+func (s *MemoryStorage) GroupObjects(boardID string, userID int, groupID string, children []string) ([]models.BoardObject, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	board, ok := s.boards[boardID]
+	if !ok {
+		return nil, ErrBoardNotFound
+	}
+	if _, exists := board.Objects[groupID]; exists {
+		return nil, fmt.Errorf("%w: %w: %s", ErrInvalidGroup, ErrObjectExists, groupID)
+	}
+	if len(board.Objects) >= models.MaxBoardObjects {
+		return nil, ErrBoardFull
+	}
+
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	group := models.BoardObject{ID: groupID, Type: models.ObjectGroup, Children: append([]string{}, children...)}
+	members := make([]models.BoardObject, 0, len(children))
+	for i, id := range children {
+		obj, ok := board.Objects[id]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, id)
+		}
+		if err := checkEditable(board, obj, userID); err != nil {
+			return nil, err
+		}
+		if obj.GroupID != "" {
+			return nil, fmt.Errorf("%w: object %s is already in group %s", ErrInvalidGroup, id, obj.GroupID)
+		}
+		if i == 0 {
+			group.LayerID = obj.LayerID
+		}
+		if obj.Z > group.Z {
+			group.Z = obj.Z
+		}
+		minX, minY = math.Min(minX, obj.X), math.Min(minY, obj.Y)
+		maxX, maxY = math.Max(maxX, obj.X+obj.Width), math.Max(maxY, obj.Y+obj.Height)
+		obj.GroupID = groupID
+		members = append(members, obj)
+	}
+	group.X, group.Y = minX, minY
+	group.Width, group.Height = maxX-minX, maxY-minY
+
+	board.Objects[groupID] = group
+	for _, obj := range members {
+		board.Objects[obj.ID] = obj
+	}
+	return append([]models.BoardObject{group}, members...), nil
+}
+
+// UngroupObjects расформировывает группу: ее объекты переходят в родительскую группу
+// или становятся самостоятельными. Возвращает измененные объекты по возрастанию ID.
+func (s *MemoryStorage) UngroupObjects(boardID string, userID int, groupID string) ([]models.BoardObject, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	board, ok := s.boards[boardID]
+	if !ok {
+		return nil, ErrBoardNotFound
+	}
+	group, ok := board.Objects[groupID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, groupID)
+	}
+	if group.Type != models.ObjectGroup {
+		return nil, fmt.Errorf("%w: object %s is not a group", ErrInvalidGroup, groupID)
+	}
+	if err := checkEditable(board, group, userID); err != nil {
+		return nil, err
+	}
+
+	changed := make(map[string]models.BoardObject, len(group.Children)+1)
+	for _, id := range group.Children {
+		obj := board.Objects[id]
+		obj.GroupID = group.GroupID
+		changed[id] = obj
+	}
+	if parent, ok := board.Objects[group.GroupID]; ok {
+		children := withoutID(parent.Children, groupID)
+		parent.Children = append(children, group.Children...)
+		changed[parent.ID] = parent
+	}
+
+	delete(board.Objects, groupID)
+	for id, obj := range changed {
+		board.Objects[id] = obj
+	}
+	return sortedObjects(changed), nil
+}
+
+// SetObjectLocked закрепляет объект или снимает закрепление
+func (s *MemoryStorage) SetObjectLocked(boardID string, objectID string, locked bool) (models.BoardObject, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	board, ok := s.boards[boardID]
+	if !ok {
+		return models.BoardObject{}, ErrBoardNotFound
+	}
+	obj, ok := board.Objects[objectID]
+	if !ok {
+		return models.BoardObject{}, ErrObjectNotFound
+	}
+
+	obj.Locked = locked
+	board.Objects[objectID] = obj
+	return obj, nil
+}
+
+// checkEditable проверяет, что объект можно менять: он не захвачен другим пользователем,
+// не закреплен и не лежит на закрепленном слое. Вызывающий держит s.mu.
+func checkEditable(board *models.Board, obj models.BoardObject, userID int) error {
+	if obj.FocusedBy != nil && *obj.FocusedBy != userID {
+		return fmt.Errorf("%w: %s", ErrObjectLocked, obj.ID)
+	}
+	if obj.Locked {
+		return fmt.Errorf("%w: %s", ErrObjectPinned, obj.ID)
+	}
+	if layer := layerLocked(board, obj.LayerID); layer != nil && layer.Locked {
+		return fmt.Errorf("%w: %s", ErrLayerLocked, layer.ID)
+	}
+	return nil
+}
+
+// descendantsLocked ID всех объектов, вложенных в группу, включая вложенные группы
+func descendantsLocked(board *models.Board, groupID string) []string {
+	var ids []string
+	for _, id := range board.Objects[groupID].Children {
+		ids = append(ids, id)
+		ids = append(ids, descendantsLocked(board, id)...)
+	}
+	return ids
+}
+
+// maxZLocked наибольший z объектов доски
+func maxZLocked(board *models.Board) int {
+	z := 0
+	for _, obj := range board.Objects {
+		if obj.Z > z {
+			z = obj.Z
+		}
+	}
+	return z
+}
+
+// sortByZ упорядочивает объекты снизу вверх; при равных z - по ID
+func sortByZ(objects []models.BoardObject) {
+	sort.Slice(objects, func(i, j int) bool {
+		if objects[i].Z != objects[j].Z {
+			return objects[i].Z < objects[j].Z
+		}
+		return objects[i].ID < objects[j].ID
+	})
+}
+
+// sortedObjects объекты карты по возрастанию ID
+func sortedObjects(objects map[string]models.BoardObject) []models.BoardObject {
+	list := make([]models.BoardObject, 0, len(objects))
+	for _, obj := range objects {
+		list = append(list, obj)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+// withoutID копия списка без указанного ID
+func withoutID(ids []string, id string) []string {
+	result := make([]string, 0, len(ids))
+	for _, other := range ids {
+		if other != id {
+			result = append(result, other)
+		}
+	}
+	return result
+}
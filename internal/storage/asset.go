@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/alexl/go-fake-api/internal/models"
+)
+
+// ErrAssetNotFound изображение не найдено
+var ErrAssetNotFound = errors.New("asset not found")
+
+// assetRefPattern ссылка на загруженное изображение в content объекта image
+var assetRefPattern = regexp.MustCompile(`/assets/([0-9A-Za-z]+)`)
+
+// CreateAsset сохраняет описание загруженного изображения
+func (s *MemoryStorage) CreateAsset(asset *models.Asset) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.boards[asset.BoardID]; !ok {
+		return ErrBoardNotFound
+	}
+	stored := *asset
+	s.assets[asset.ID] = &stored
+	return nil
+}
+
+// GetAsset возвращает изображение по ID
+func (s *MemoryStorage) GetAsset(id string) (models.Asset, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	asset, ok := s.assets[id]
+	if !ok {
+		return models.Asset{}, ErrAssetNotFound
+	}
+	return *asset, nil
+}
+
+// GetBoardAssets возвращает изображения, загруженные на доску, от старых к новым
+func (s *MemoryStorage) GetBoardAssets(boardID string) ([]models.Asset, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.boards[boardID]; !ok {
+		return nil, ErrBoardNotFound
+	}
+
+	assets := []models.Asset{}
+	for _, asset := range s.assets {
+		if asset.BoardID == boardID {
+			assets = append(assets, *asset)
+		}
+	}
+	sort.Slice(assets, func(i, j int) bool {
+		if !assets[i].CreatedAt.Equal(assets[j].CreatedAt) {
+			return assets[i].CreatedAt.Before(assets[j].CreatedAt)
+		}
+		return assets[i].ID < assets[j].ID
+	})
+	return assets, nil
+}
+
+// CollectAssets удаляет описания изображений, загруженных раньше before, на которые
+// не ссылается ни один объект image на любой доске и в любом снимке. Копии досок
+// ссылаются на те же файлы, поэтому ссылки ищутся по всем доскам.
+// Возвращает удаленные изображения, их файлы удаляет вызывающий.
+func (s *MemoryStorage) CollectAssets(before time.Time) []models.Asset {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	referenced := make(map[string]bool)
+	mark := func(objects map[string]models.BoardObject) {
+		for _, obj := range objects {
+			if obj.Type != models.ObjectImage {
+				continue
+			}
+			for _, match := range assetRefPattern.FindAllStringSubmatch(obj.Content, -1) {
+				referenced[match[1]] = true
+			}
+		}
+	}
+	for _, board := range s.boards {
+		mark(board.Objects)
+	}
+	for _, snapshots := range s.snapshots {
+		for _, snapshot := range snapshots {
+			mark(snapshot.Objects)
+		}
+	}
+
+	var collected []models.Asset
+	for id, asset := range s.assets {
+		if !referenced[id] && asset.CreatedAt.Before(before) {
+			collected = append(collected, *asset)
+			delete(s.assets, id)
+		}
+	}
+	sort.Slice(collected, func(i, j int) bool {
+		return collected[i].ID < collected[j].ID
+	})
+	return collected
+}
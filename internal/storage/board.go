@@ -2,18 +2,44 @@ package storage
 
 import (
 	"errors"
+	"fmt"
 	"sort"
+	"time"
 
 	"github.com/alexl/go-fake-api/internal/models"
 )
 
+// Ошибки операций над объектами
+var (
+	ErrObjectNotFound = errors.New("object not found")
+	ErrObjectExists   = errors.New("object already exists")
+	ErrObjectLocked   = errors.New("object is focused by another user")
+	ErrObjectPinned   = errors.New("object is locked by the board owner")
+	ErrInvalidGroup   = errors.New("invalid group")
+	ErrBoardFull      = fmt.Errorf("board can not contain more than %d objects", models.MaxBoardObjects)
+)
+
 // CreateBoard создает новую доску
 func (s *MemoryStorage) CreateBoard(board *models.Board) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if _, exists := s.boardsByHash[board.Hash]; exists {
+		return errors.New("board hash already in use")
+	}
+
+	if board.UpdatedAt.IsZero() {
+		board.UpdatedAt = board.CreatedAt
+	}
+	if board.Tags == nil {
+		board.Tags = []string{}
+	}
+	if board.Layers == nil {
+		board.Layers = []models.Layer{}
+	}
 	s.boards[board.ID] = board
-	s.boardAccess[board.ID] = append(s.boardAccess[board.ID], board.OwnerID)
+	s.boardsByHash[board.Hash] = board
+	s.addBoardAccess(board.ID, board.OwnerID, models.RoleOwner)
 	s.boardLikes[board.ID] = make(map[int]bool)
 	return nil
 }
@@ -35,12 +61,59 @@ func (s *MemoryStorage) GetBoardByHash(hash string) (*models.Board, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	for _, board := range s.boards {
-		if board.Hash == hash {
-			return board, nil
-		}
+	board, ok := s.boardsByHash[hash]
+	if !ok {
+		return nil, errors.New("board not found")
 	}
-	return nil, errors.New("board not found")
+	return board, nil
+}
+
+// UpdateBoardHash заменяет публичный хеш доски, старая ссылка перестает работать
+func (s *MemoryStorage) UpdateBoardHash(boardID string, hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	board, ok := s.boards[boardID]
+	if !ok {
+		return errors.New("board not found")
+	}
+
+	if _, exists := s.boardsByHash[hash]; exists {
+		return errors.New("board hash already in use")
+	}
+
+	delete(s.boardsByHash, board.Hash)
+	board.Hash = hash
+	s.boardsByHash[hash] = board
+	return nil
+}
+
+// SetBoardLinkSharing включает или выключает доступ к приватной доске по ссылке
+func (s *MemoryStorage) SetBoardLinkSharing(boardID string, enabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	board, ok := s.boards[boardID]
+	if !ok {
+		return errors.New("board not found")
+	}
+
+	board.LinkSharing = enabled
+	return nil
+}
+
+// SetBoardTemplate публикует доску в галерее шаблонов или снимает с нее
+func (s *MemoryStorage) SetBoardTemplate(boardID string, enabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	board, ok := s.boards[boardID]
+	if !ok {
+		return errors.New("board not found")
+	}
+
+	board.IsTemplate = enabled
+	return nil
 }
 
 // GetUserBoards возвращает список досок пользователя
@@ -53,15 +126,39 @@ func (s *MemoryStorage) GetUserBoards(userID int) ([]models.Board, error) {
 		for _, uid := range userIDs {
 			if uid == userID {
 				if board, ok := s.boards[boardID]; ok {
-					userBoards = append(userBoards, *board)
+					userBoards = append(userBoards, snapshotLocked(board))
 				}
 				break
 			}
 		}
 	}
+
+	// Порядок карты случаен: отдаем доски в порядке создания
+	less := boardLess(models.BoardSortCreatedAt, false)
+	sort.Slice(userBoards, func(i, j int) bool {
+		return less(userBoards[i], userBoards[j])
+	})
 	return userBoards, nil
 }
 
+// snapshotLocked копия доски для списков, вызывающий держит s.mu.
+// Карта объектов меняется на месте, поэтому копируется: иначе кодирование ответа
+// после снятия блокировки гонялось бы с записью. Слои и теги заменяются целиком.
+func snapshotLocked(board *models.Board) models.Board {
+	snapshot := *board
+	snapshot.Objects = copyObjects(board.Objects)
+	return snapshot
+}
+
+// copyObjects копия карты объектов доски
+func copyObjects(objects map[string]models.BoardObject) map[string]models.BoardObject {
+	copied := make(map[string]models.BoardObject, len(objects))
+	for id, obj := range objects {
+		copied[id] = obj
+	}
+	return copied
+}
+
 // GetPublicBoards возвращает список публичных досок
 func (s *MemoryStorage) GetPublicBoards() ([]models.Board, error) {
 	s.mu.RLock()
@@ -70,7 +167,7 @@ func (s *MemoryStorage) GetPublicBoards() ([]models.Board, error) {
 	var publicBoards []models.Board
 	for _, board := range s.boards {
 		if board.IsPublic {
-			publicBoards = append(publicBoards, *board)
+			publicBoards = append(publicBoards, snapshotLocked(board))
 		}
 	}
 
@@ -82,6 +179,81 @@ func (s *MemoryStorage) GetPublicBoards() ([]models.Board, error) {
 	return publicBoards, nil
 }
 
+// GetBoardObject возвращает копию объекта доски
+func (s *MemoryStorage) GetBoardObject(boardID string, objectID string) (models.BoardObject, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	board, ok := s.boards[boardID]
+	if !ok {
+		return models.BoardObject{}, errors.New("board not found")
+	}
+
+	obj, ok := board.Objects[objectID]
+	if !ok {
+		return models.BoardObject{}, ErrObjectNotFound
+	}
+	return obj, nil
+}
+
+// SetObjectFocus захватывает объект пользователем (focus) или снимает его захват.
+// Проверка и запись идут под одной блокировкой, поэтому двое не захватят объект
+// одновременно, а геометрия объекта не откатится к прочитанной ранее.
+// Возвращает false, если снимать было нечего.
+func (s *MemoryStorage) SetObjectFocus(boardID string, objectID string, userID int, userName string, focus bool, now time.Time) (models.BoardObject, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	board, ok := s.boards[boardID]
+	if !ok {
+		return models.BoardObject{}, false, ErrBoardNotFound
+	}
+	obj, ok := board.Objects[objectID]
+	if !ok {
+		return models.BoardObject{}, false, ErrObjectNotFound
+	}
+
+	if obj.FocusedBy == nil && !focus {
+		return obj, false, nil
+	}
+	if obj.FocusedBy != nil && *obj.FocusedBy != userID {
+		return obj, false, ErrObjectLocked
+	}
+
+	if focus {
+		focusedBy := userID
+		obj.FocusedBy = &focusedBy
+		obj.FocusedAt = &now
+		obj.OwnerName = userName
+	} else {
+		obj.FocusedBy = nil
+		obj.FocusedAt = nil
+		obj.OwnerName = ""
+	}
+	board.Objects[objectID] = obj
+	return obj, true, nil
+}
+
+// GetBoardObjects возвращает объекты доски, отсортированные по ID
+func (s *MemoryStorage) GetBoardObjects(boardID string) ([]models.BoardObject, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	board, ok := s.boards[boardID]
+	if !ok {
+		return nil, errors.New("board not found")
+	}
+
+	objects := make([]models.BoardObject, 0, len(board.Objects))
+	for _, obj := range board.Objects {
+		objects = append(objects, obj)
+	}
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].ID < objects[j].ID
+	})
+	return objects, nil
+}
+
 // UpdateBoardObject обновление или добавление объекта на доске
 func (s *MemoryStorage) UpdateBoardObject(boardID string, obj models.BoardObject) error {
 	s.mu.Lock()
@@ -99,6 +271,195 @@ func (s *MemoryStorage) UpdateBoardObject(boardID string, obj models.BoardObject
 	return nil
 }
 
+// ApplyObjectsBatch атомарно применяет обновления и удаления объектов от имени пользователя.
+// Если хотя бы один объект захвачен другим пользователем, закреплен, лежит на закрепленном
+// слое или удаляемого объекта нет, ничего не меняется. Обновления сохраняют фокус, z,
+// группу и закрепление объектов, новые объекты кладутся поверх остальных.
+// Перемещение группы сдвигает входящие в нее объекты, удаление группы удаляет их.
+// Соединители перестраиваются вслед за своими концами и удаляются вместе с ними.
+// Результат - сначала запрошенные изменения, затем побочные, по возрастанию ID.
+func (s *MemoryStorage) ApplyObjectsBatch(boardID string, userID int, updates []models.BoardObject, deletes []string) (models.ObjectsBatch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	board, ok := s.boards[boardID]
+	if !ok {
+		return models.ObjectsBatch{}, errors.New("board not found")
+	}
+	return applyObjectsLocked(board, userID, updates, deletes)
+}
+
+// CreateBoardObject добавляет новый объект так же, как ApplyObjectsBatch, но не заменяет
+// существующий: занятый ID - ErrObjectExists. Проверка идет под той же блокировкой,
+// что и запись, поэтому одновременные создания с одним ID не перезапишут друг друга.
+func (s *MemoryStorage) CreateBoardObject(boardID string, userID int, obj models.BoardObject) (models.ObjectsBatch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	board, ok := s.boards[boardID]
+	if !ok {
+		return models.ObjectsBatch{}, ErrBoardNotFound
+	}
+	if _, exists := board.Objects[obj.ID]; exists {
+		return models.ObjectsBatch{}, fmt.Errorf("%w: %s", ErrObjectExists, obj.ID)
+	}
+	return applyObjectsLocked(board, userID, []models.BoardObject{obj}, nil)
+}
+
+// applyObjectsLocked применяет пакет к доске, вызывающий держит s.mu
+func applyObjectsLocked(board *models.Board, userID int, updates []models.BoardObject, deletes []string) (models.ObjectsBatch, error) {
+	if board.Objects == nil {
+		board.Objects = make(map[string]models.BoardObject)
+	}
+
+	// Сначала проверяем весь пакет на рабочей копии изменений, затем применяем
+	changed := make(map[string]models.BoardObject)
+	removed := make(map[string]bool)
+	current := func(id string) (models.BoardObject, bool) {
+		if obj, ok := changed[id]; ok {
+			return obj, true
+		}
+		obj, ok := board.Objects[id]
+		return obj, ok
+	}
+
+	z := maxZLocked(board)
+	applied := make([]models.BoardObject, len(updates))
+	for i, obj := range updates {
+		existing, ok := board.Objects[obj.ID]
+		if ok {
+			if err := checkEditable(board, existing, userID); err != nil {
+				return models.ObjectsBatch{}, err
+			}
+			if (obj.Type == models.ObjectGroup) != (existing.Type == models.ObjectGroup) {
+				return models.ObjectsBatch{}, fmt.Errorf("%w: type of %s can not change to or from group", ErrInvalidGroup, obj.ID)
+			}
+			obj.FocusedBy = existing.FocusedBy
+			obj.FocusedAt = existing.FocusedAt
+			obj.OwnerName = existing.OwnerName
+			obj.Z = existing.Z
+			obj.GroupID = existing.GroupID
+			obj.Children = existing.Children
+			obj.Locked = existing.Locked
+		} else {
+			if obj.Type == models.ObjectGroup {
+				return models.ObjectsBatch{}, fmt.Errorf("%w: groups are created by grouping objects", ErrInvalidGroup)
+			}
+			z++
+			obj.FocusedBy = nil
+			obj.FocusedAt = nil
+			obj.OwnerName = ""
+			obj.Z = z
+			obj.GroupID = ""
+			obj.Children = nil
+			obj.Locked = false
+		}
+		if obj.LayerID != existing.LayerID || !ok {
+			if err := checkLayer(board, obj.LayerID); err != nil {
+				return models.ObjectsBatch{}, err
+			}
+		}
+		applied[i] = obj
+		changed[obj.ID] = obj
+	}
+
+	// Группа двигается вместе со своими объектами, кроме тех, что пакет меняет явно
+	for _, obj := range applied {
+		existing, ok := board.Objects[obj.ID]
+		if !ok || obj.Type != models.ObjectGroup {
+			continue
+		}
+		dx, dy := obj.X-existing.X, obj.Y-existing.Y
+		if dx == 0 && dy == 0 {
+			continue
+		}
+		for _, id := range descendantsLocked(board, obj.ID) {
+			if _, explicit := changed[id]; explicit {
+				continue
+			}
+			child := board.Objects[id]
+			if err := checkEditable(board, child, userID); err != nil {
+				return models.ObjectsBatch{}, err
+			}
+			child.X += dx
+			child.Y += dy
+			changed[id] = child
+		}
+	}
+
+	// Удаление группы удаляет вложенные объекты
+	var cascade []string
+	for _, objectID := range deletes {
+		if _, ok := board.Objects[objectID]; !ok {
+			return models.ObjectsBatch{}, fmt.Errorf("%w: %s", ErrObjectNotFound, objectID)
+		}
+		removed[objectID] = true
+	}
+	for _, objectID := range deletes {
+		for _, id := range descendantsLocked(board, objectID) {
+			if !removed[id] {
+				removed[id] = true
+				cascade = append(cascade, id)
+			}
+		}
+	}
+	for id := range removed {
+		if err := checkEditable(board, board.Objects[id], userID); err != nil {
+			return models.ObjectsBatch{}, err
+		}
+		delete(changed, id)
+	}
+
+	sort.Strings(cascade)
+	connectors, err := followConnectorsLocked(board, changed, removed)
+	if err != nil {
+		return models.ObjectsBatch{}, err
+	}
+	cascade = append(cascade, connectors...)
+
+	// Удаленный объект выходит из группы, если сама группа остается
+	for id := range removed {
+		groupID := board.Objects[id].GroupID
+		if groupID == "" || removed[groupID] {
+			continue
+		}
+		group, _ := current(groupID)
+		group.Children = withoutID(group.Children, id)
+		changed[groupID] = group
+	}
+
+	// Пакет, увеличивающий число объектов сверх лимита, отклоняется; уменьшать доску можно всегда
+	created := 0
+	for _, obj := range applied {
+		if _, ok := board.Objects[obj.ID]; !ok {
+			created++
+		}
+	}
+	if created > len(removed) && len(board.Objects)+created-len(removed) > models.MaxBoardObjects {
+		return models.ObjectsBatch{}, ErrBoardFull
+	}
+
+	result := models.ObjectsBatch{Updates: make([]models.BoardObject, 0, len(changed)), Deletes: make([]string, 0, len(removed))}
+	for _, obj := range applied {
+		if !removed[obj.ID] {
+			result.Updates = append(result.Updates, changed[obj.ID])
+			delete(changed, obj.ID)
+		}
+	}
+	result.Updates = append(result.Updates, sortedObjects(changed)...)
+	result.Deletes = append(result.Deletes, deletes...)
+	result.Deletes = append(result.Deletes, cascade...)
+
+	for _, obj := range result.Updates {
+		board.Objects[obj.ID] = obj
+	}
+	for _, objectID := range result.Deletes {
+		delete(board.Objects, objectID)
+	}
+
+	return result, nil
+}
+
 // DeleteBoardObject удаляет объект с доски
 func (s *MemoryStorage) DeleteBoardObject(boardID string, objectID string) error {
 	s.mu.Lock()
@@ -113,48 +474,133 @@ func (s *MemoryStorage) DeleteBoardObject(boardID string, objectID string) error
 	return nil
 }
 
-// AddBoardAccess предоставляет доступ к доске
-func (s *MemoryStorage) AddBoardAccess(boardID string, userID int) error {
+// AddBoardAccess предоставляет доступ к доске с указанной ролью.
+// Повторный вызов меняет роль уже имеющего доступ пользователя.
+func (s *MemoryStorage) AddBoardAccess(boardID string, userID int, role string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	s.addBoardAccess(boardID, userID, role)
+	return nil
+}
+
+// addBoardAccess добавляет доступ без блокировки, вызывающий держит s.mu
+func (s *MemoryStorage) addBoardAccess(boardID string, userID int, role string) {
+	if s.boardRoles[boardID] == nil {
+		s.boardRoles[boardID] = make(map[int]string)
+	}
+	s.boardRoles[boardID][userID] = role
+
 	// Проверяем, есть ли уже доступ
 	for _, uid := range s.boardAccess[boardID] {
 		if uid == userID {
-			return nil
+			return
 		}
 	}
 
 	s.boardAccess[boardID] = append(s.boardAccess[boardID], userID)
-	return nil
 }
 
-// HasBoardAccess проверяет наличие доступа
-func (s *MemoryStorage) HasBoardAccess(boardID string, userID int) (bool, error) {
+// RemoveBoardAccess забирает доступ к доске. Владельца лишить доступа нельзя.
+func (s *MemoryStorage) RemoveBoardAccess(boardID string, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	board, ok := s.boards[boardID]
+	if !ok {
+		return errors.New("board not found")
+	}
+	if board.OwnerID == userID {
+		return errors.New("can not remove owner access")
+	}
+
+	userIDs := s.boardAccess[boardID]
+	for i, uid := range userIDs {
+		if uid == userID {
+			s.boardAccess[boardID] = append(userIDs[:i:i], userIDs[i+1:]...)
+			delete(s.boardRoles[boardID], userID)
+			return nil
+		}
+	}
+	return errors.New("user has no access")
+}
+
+// GetBoardMembers возвращает участников доски в порядке выдачи доступа
+func (s *MemoryStorage) GetBoardMembers(boardID string) ([]models.BoardAccess, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	userIDs, ok := s.boardAccess[boardID]
+	board, ok := s.boards[boardID]
 	if !ok {
-		return false, nil
+		return nil, errors.New("board not found")
 	}
 
-	for _, uid := range userIDs {
+	members := []models.BoardAccess{}
+	for _, uid := range s.boardAccess[boardID] {
+		user, ok := s.users[uid]
+		if !ok {
+			continue
+		}
+		role := s.boardRoles[boardID][uid]
+		if uid == board.OwnerID {
+			role = models.RoleOwner
+		} else if role == "" {
+			role = models.RoleEditor
+		}
+		members = append(members, models.BoardAccess{
+			BoardID: boardID,
+			UserID:  uid,
+			Name:    user.Name,
+			Email:   user.Email,
+			Role:    role,
+		})
+	}
+	return members, nil
+}
+
+// HasBoardAccess проверяет наличие доступа
+func (s *MemoryStorage) HasBoardAccess(boardID string, userID int) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.hasBoardAccess(boardID, userID), nil
+}
+
+// hasBoardAccess проверяет доступ без блокировки, вызывающий держит s.mu
+func (s *MemoryStorage) hasBoardAccess(boardID string, userID int) bool {
+	for _, uid := range s.boardAccess[boardID] {
 		if uid == userID {
-			return true, nil
+			return true
 		}
 	}
-	return false, nil
+	return false
 }
 
-// LikeBoard ставит/снимает лайк
-func (s *MemoryStorage) LikeBoard(boardID string, userID int) error {
+// GetBoardRole возвращает роль пользователя на доске или пустую строку, если доступа нет
+func (s *MemoryStorage) GetBoardRole(boardID string, userID int) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if board, ok := s.boards[boardID]; ok && board.OwnerID == userID {
+		return models.RoleOwner, nil
+	}
+	if !s.hasBoardAccess(boardID, userID) {
+		return "", nil
+	}
+	if role, ok := s.boardRoles[boardID][userID]; ok {
+		return role, nil
+	}
+	return models.RoleEditor, nil
+}
+
+// LikeBoard ставит/снимает лайк. Возвращает true, если лайк поставлен.
+func (s *MemoryStorage) LikeBoard(boardID string, userID int) (bool, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	board, ok := s.boards[boardID]
 	if !ok {
-		return errors.New("board not found")
+		return false, errors.New("board not found")
 	}
 
 	if s.boardLikes[boardID] == nil {
@@ -165,11 +611,11 @@ func (s *MemoryStorage) LikeBoard(boardID string, userID int) error {
 		// Убираем лайк
 		delete(s.boardLikes[boardID], userID)
 		board.Likes--
-	} else {
-		// Ставим лайк
-		s.boardLikes[boardID][userID] = true
-		board.Likes++
+		return false, nil
 	}
 
-	return nil
+	// Ставим лайк
+	s.boardLikes[boardID][userID] = true
+	board.Likes++
+	return true, nil
 }
@@ -0,0 +1,262 @@
+package storage
+
+import (
+	"errors"
+	"time"
+
+	"github.com/alexl/go-fake-api/internal/models"
+)
+
+// Ошибки чата и комментариев
+var (
+	ErrBoardNotFound   = errors.New("board not found")
+	ErrMessageNotFound = errors.New("message not found")
+	ErrCommentNotFound = errors.New("comment not found")
+	ErrCommentParent   = errors.New("parent comment must be a thread root of the same object")
+)
+
+// CreateChatMessage сохраняет сообщение чата и выдает ему ID
+func (s *MemoryStorage) CreateChatMessage(msg *models.ChatMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.boards[msg.BoardID]; !ok {
+		return ErrBoardNotFound
+	}
+
+	msg.ID = s.nextMessageIDLocked()
+	stored := *msg
+	s.chatMessages[msg.BoardID] = append(s.chatMessages[msg.BoardID], &stored)
+	return nil
+}
+
+// GetChatMessage возвращает сообщение чата доски
+func (s *MemoryStorage) GetChatMessage(boardID string, id int) (models.ChatMessage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i := s.chatIndexLocked(boardID, id)
+	if i < 0 {
+		return models.ChatMessage{}, ErrMessageNotFound
+	}
+	return *s.chatMessages[boardID][i], nil
+}
+
+// UpdateChatMessage меняет текст сообщения и отмечает время правки
+func (s *MemoryStorage) UpdateChatMessage(boardID string, id int, text string, mentions []int, editedAt time.Time) (models.ChatMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.chatIndexLocked(boardID, id)
+	if i < 0 {
+		return models.ChatMessage{}, ErrMessageNotFound
+	}
+
+	msg := s.chatMessages[boardID][i]
+	msg.Text = text
+	msg.Mentions = mentions
+	msg.EditedAt = &editedAt
+	return *msg, nil
+}
+
+// DeleteChatMessage удаляет сообщение чата
+func (s *MemoryStorage) DeleteChatMessage(boardID string, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.chatIndexLocked(boardID, id)
+	if i < 0 {
+		return ErrMessageNotFound
+	}
+
+	messages := s.chatMessages[boardID]
+	s.chatMessages[boardID] = append(messages[:i], messages[i+1:]...)
+	return nil
+}
+
+// GetChatMessages возвращает до limit последних сообщений с ID меньше before
+// (before = 0 - с конца чата) в хронологическом порядке и признак более ранних сообщений
+func (s *MemoryStorage) GetChatMessages(boardID string, before int, limit int) ([]models.ChatMessage, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.boards[boardID]; !ok {
+		return nil, false, ErrBoardNotFound
+	}
+
+	messages := s.chatMessages[boardID]
+	end := len(messages)
+	if before > 0 {
+		for end > 0 && messages[end-1].ID >= before {
+			end--
+		}
+	}
+	start := end - limit
+	if start < 0 {
+		start = 0
+	}
+
+	page := make([]models.ChatMessage, 0, end-start)
+	for _, msg := range messages[start:end] {
+		page = append(page, *msg)
+	}
+	return page, start > 0, nil
+}
+
+// CreateComment сохраняет комментарий к объекту доски и выдает ему ID.
+// Ответ можно оставить только в корневой комментарий того же объекта.
+func (s *MemoryStorage) CreateComment(comment *models.Comment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	board, ok := s.boards[comment.BoardID]
+	if !ok {
+		return ErrBoardNotFound
+	}
+	if _, ok := board.Objects[comment.ObjectID]; !ok {
+		return ErrObjectNotFound
+	}
+	if comment.ParentID != 0 {
+		i := s.commentIndexLocked(comment.BoardID, comment.ParentID)
+		if i < 0 {
+			return ErrCommentParent
+		}
+		parent := s.comments[comment.BoardID][i]
+		if parent.ObjectID != comment.ObjectID || parent.ParentID != 0 {
+			return ErrCommentParent
+		}
+	}
+
+	comment.ID = s.nextMessageIDLocked()
+	stored := *comment
+	s.comments[comment.BoardID] = append(s.comments[comment.BoardID], &stored)
+	return nil
+}
+
+// GetComment возвращает комментарий доски
+func (s *MemoryStorage) GetComment(boardID string, id int) (models.Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i := s.commentIndexLocked(boardID, id)
+	if i < 0 {
+		return models.Comment{}, ErrCommentNotFound
+	}
+	return *s.comments[boardID][i], nil
+}
+
+// UpdateComment меняет текст комментария и отмечает время правки
+func (s *MemoryStorage) UpdateComment(boardID string, id int, text string, mentions []int, editedAt time.Time) (models.Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.commentIndexLocked(boardID, id)
+	if i < 0 {
+		return models.Comment{}, ErrCommentNotFound
+	}
+
+	comment := s.comments[boardID][i]
+	comment.Text = text
+	comment.Mentions = mentions
+	comment.EditedAt = &editedAt
+	return *comment, nil
+}
+
+// DeleteComment удаляет комментарий, а если это корень ветки - и все ответы в ней.
+// Возвращает ID удаленных ответов.
+func (s *MemoryStorage) DeleteComment(boardID string, id int) ([]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.commentIndexLocked(boardID, id) < 0 {
+		return nil, ErrCommentNotFound
+	}
+
+	replies := []int{}
+	kept := s.comments[boardID][:0]
+	for _, comment := range s.comments[boardID] {
+		switch {
+		case comment.ID == id:
+		case comment.ParentID == id:
+			replies = append(replies, comment.ID)
+		default:
+			kept = append(kept, comment)
+		}
+	}
+	// Хвост среза больше не используется, не держим в нем удаленные комментарии
+	for i := len(kept); i < len(s.comments[boardID]); i++ {
+		s.comments[boardID][i] = nil
+	}
+	s.comments[boardID] = kept
+	return replies, nil
+}
+
+// GetCommentThreads возвращает до limit последних веток объекта с ID корня меньше before
+// (before = 0 - с самой новой) в хронологическом порядке и признак более ранних веток
+func (s *MemoryStorage) GetCommentThreads(boardID string, objectID string, before int, limit int) ([]models.CommentThread, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.boards[boardID]; !ok {
+		return nil, false, ErrBoardNotFound
+	}
+
+	// Комментарии хранятся в порядке создания, поэтому корни и ответы уже упорядочены
+	var roots []int
+	replies := make(map[int][]models.Comment)
+	for i, comment := range s.comments[boardID] {
+		if comment.ObjectID != objectID {
+			continue
+		}
+		if comment.ParentID == 0 {
+			if before == 0 || comment.ID < before {
+				roots = append(roots, i)
+			}
+		} else {
+			replies[comment.ParentID] = append(replies[comment.ParentID], *comment)
+		}
+	}
+
+	start := len(roots) - limit
+	if start < 0 {
+		start = 0
+	}
+
+	threads := make([]models.CommentThread, 0, len(roots)-start)
+	for _, i := range roots[start:] {
+		root := *s.comments[boardID][i]
+		thread := models.CommentThread{Comment: root, Replies: replies[root.ID]}
+		if thread.Replies == nil {
+			thread.Replies = []models.Comment{}
+		}
+		threads = append(threads, thread)
+	}
+	return threads, start > 0, nil
+}
+
+// nextMessageIDLocked выдает ID сообщения чата или комментария. Общий счетчик
+// возрастает со временем, поэтому ID служит курсором пагинации.
+func (s *MemoryStorage) nextMessageIDLocked() int {
+	s.messageIDCounter++
+	return s.messageIDCounter
+}
+
+// chatIndexLocked ищет позицию сообщения в чате доски, -1 если его нет
+func (s *MemoryStorage) chatIndexLocked(boardID string, id int) int {
+	for i, msg := range s.chatMessages[boardID] {
+		if msg.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// commentIndexLocked ищет позицию комментария среди комментариев доски, -1 если его нет
+func (s *MemoryStorage) commentIndexLocked(boardID string, id int) int {
+	for i, comment := range s.comments[boardID] {
+		if comment.ID == id {
+			return i
+		}
+	}
+	return -1
+}
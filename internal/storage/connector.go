@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/alexl/go-fake-api/internal/models"
+)
+
+// ErrInvalidConnector концы соединителя не подходят
+var ErrInvalidConnector = errors.New("invalid connector")
+
+// followConnectorsLocked проводит соединители пакета и те, чьи концы пакет меняет, и удаляет
+// соединители, чьи концы удаляются. Соединители следуют за концами, даже если закреплены.
+// Возвращает ID удаленных соединителей по возрастанию.
+func followConnectorsLocked(board *models.Board, changed map[string]models.BoardObject, removed map[string]bool) ([]string, error) {
+	current := func(id string) (models.BoardObject, bool) {
+		if removed[id] {
+			return models.BoardObject{}, false
+		}
+		if obj, ok := changed[id]; ok {
+			return obj, true
+		}
+		obj, ok := board.Objects[id]
+		return obj, ok
+	}
+
+	// Явно измененные соединители должны ссылаться на существующие объекты
+	for id, conn := range changed {
+		if conn.Type != models.ObjectConnector {
+			continue
+		}
+		from, okFrom := current(conn.From)
+		to, okTo := current(conn.To)
+		switch {
+		case !okFrom || !okTo:
+			return nil, fmt.Errorf("%w: %s must connect existing objects", ErrInvalidConnector, id)
+		case from.Type == models.ObjectConnector || to.Type == models.ObjectConnector:
+			return nil, fmt.Errorf("%w: %s can not connect other connectors", ErrInvalidConnector, id)
+		}
+		changed[id] = routeConnector(conn, from, to)
+	}
+
+	var deleted []string
+	for id, conn := range board.Objects {
+		if conn.Type != models.ObjectConnector || removed[id] {
+			continue
+		}
+		if _, explicit := changed[id]; explicit {
+			continue
+		}
+		from, okFrom := current(conn.From)
+		to, okTo := current(conn.To)
+		if !okFrom || !okTo {
+			removed[id] = true
+			deleted = append(deleted, id)
+			continue
+		}
+		_, fromChanged := changed[conn.From]
+		_, toChanged := changed[conn.To]
+		if fromChanged || toChanged {
+			changed[id] = routeConnector(conn, from, to)
+		}
+	}
+	sort.Strings(deleted)
+	return deleted, nil
+}
+
+// routeConnector проводит соединитель прямой от границы одного объекта до границы другого:
+// x, y - начало, points - начало и конец относительно x, y, рамка охватывает линию
+func routeConnector(conn, from, to models.BoardObject) models.BoardObject {
+	fx, fy := center(from)
+	tx, ty := center(to)
+	sx, sy := edgePoint(from, tx-fx, ty-fy)
+	ex, ey := edgePoint(to, fx-tx, fy-ty)
+
+	conn.X, conn.Y = sx, sy
+	conn.Points = []float64{0, 0, ex - sx, ey - sy}
+	conn.Width, conn.Height = math.Abs(ex-sx), math.Abs(ey-sy)
+	conn.Rotation = 0
+	return conn
+}
+
+// center центр рамки объекта
+func center(obj models.BoardObject) (float64, float64) {
+	return obj.X + obj.Width/2, obj.Y + obj.Height/2
+}
+
+// edgePoint точка выхода луча из центра объекта в направлении (dx, dy) через его рамку
+func edgePoint(obj models.BoardObject, dx, dy float64) (float64, float64) {
+	cx, cy := center(obj)
+	scale := 1.0
+	if dx != 0 {
+		scale = math.Min(scale, obj.Width/2/math.Abs(dx))
+	}
+	if dy != 0 {
+		scale = math.Min(scale, obj.Height/2/math.Abs(dy))
+	}
+	return cx + dx*scale, cy + dy*scale
+}
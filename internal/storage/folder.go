@@ -0,0 +1,204 @@
+package storage
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/alexl/go-fake-api/internal/models"
+)
+
+// Ошибки папок
+var (
+	ErrFolderNotFound = errors.New("folder not found")
+	ErrFolderCycle    = errors.New("folder cannot be moved into itself or its subfolder")
+	ErrFolderDepth    = errors.New("folder nesting is too deep")
+)
+
+// CreateFolder сохраняет папку пользователя и выдает ей ID
+func (s *MemoryStorage) CreateFolder(folder *models.Folder) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if folder.ParentID != 0 {
+		if s.userFolderLocked(folder.UserID, folder.ParentID) == nil {
+			return ErrFolderNotFound
+		}
+		if s.folderDepthLocked(folder.ParentID)+1 > models.MaxFolderDepth {
+			return ErrFolderDepth
+		}
+	}
+
+	s.folderIDCounter++
+	folder.ID = s.folderIDCounter
+	stored := *folder
+	s.folders[folder.ID] = &stored
+	return nil
+}
+
+// GetFolders возвращает папки пользователя по порядку создания
+func (s *MemoryStorage) GetFolders(userID int) ([]models.Folder, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	folders := []models.Folder{}
+	for _, folder := range s.folders {
+		if folder.UserID == userID {
+			folders = append(folders, *folder)
+		}
+	}
+	sort.Slice(folders, func(i, j int) bool {
+		return folders[i].ID < folders[j].ID
+	})
+	return folders, nil
+}
+
+// UpdateFolder переименовывает папку и/или переносит ее в другую (nil - не менять).
+// Папку нельзя перенести в нее саму или в ее подпапку.
+func (s *MemoryStorage) UpdateFolder(userID int, id int, name *string, parentID *int) (models.Folder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	folder := s.userFolderLocked(userID, id)
+	if folder == nil {
+		return models.Folder{}, ErrFolderNotFound
+	}
+
+	if parentID != nil && *parentID != 0 {
+		if s.userFolderLocked(userID, *parentID) == nil {
+			return models.Folder{}, ErrFolderNotFound
+		}
+		for p := *parentID; p != 0; p = s.folders[p].ParentID {
+			if p == id {
+				return models.Folder{}, ErrFolderCycle
+			}
+		}
+		if s.folderDepthLocked(*parentID)+s.subtreeHeightLocked(id) > models.MaxFolderDepth {
+			return models.Folder{}, ErrFolderDepth
+		}
+	}
+
+	if name != nil {
+		folder.Name = *name
+	}
+	if parentID != nil {
+		folder.ParentID = *parentID
+	}
+	return *folder, nil
+}
+
+// DeleteFolder удаляет папку. Вложенные папки и доски переходят в родительскую папку.
+func (s *MemoryStorage) DeleteFolder(userID int, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	folder := s.userFolderLocked(userID, id)
+	if folder == nil {
+		return ErrFolderNotFound
+	}
+
+	for _, child := range s.folders {
+		if child.ParentID == id {
+			child.ParentID = folder.ParentID
+		}
+	}
+	for boardID, folderID := range s.boardFolders[userID] {
+		if folderID != id {
+			continue
+		}
+		if folder.ParentID == 0 {
+			delete(s.boardFolders[userID], boardID)
+		} else {
+			s.boardFolders[userID][boardID] = folder.ParentID
+		}
+	}
+	delete(s.folders, id)
+	return nil
+}
+
+// SetBoardFolder кладет доску в папку пользователя, folderID = 0 убирает ее из папки
+func (s *MemoryStorage) SetBoardFolder(userID int, boardID string, folderID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.boards[boardID]; !ok {
+		return ErrBoardNotFound
+	}
+	if folderID == 0 {
+		delete(s.boardFolders[userID], boardID)
+		return nil
+	}
+	if s.userFolderLocked(userID, folderID) == nil {
+		return ErrFolderNotFound
+	}
+
+	if s.boardFolders[userID] == nil {
+		s.boardFolders[userID] = make(map[string]int)
+	}
+	s.boardFolders[userID][boardID] = folderID
+	return nil
+}
+
+// SetBoardFavorite добавляет доску в избранное пользователя или убирает из него
+func (s *MemoryStorage) SetBoardFavorite(userID int, boardID string, favorite bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.boards[boardID]; !ok {
+		return ErrBoardNotFound
+	}
+	if !favorite {
+		delete(s.favorites[userID], boardID)
+		return nil
+	}
+
+	if s.favorites[userID] == nil {
+		s.favorites[userID] = make(map[string]bool)
+	}
+	s.favorites[userID][boardID] = true
+	return nil
+}
+
+// SetBoardTags заменяет теги доски. Теги должны быть уже нормализованы.
+func (s *MemoryStorage) SetBoardTags(boardID string, tags []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	board, ok := s.boards[boardID]
+	if !ok {
+		return ErrBoardNotFound
+	}
+	// Срез заменяется целиком: копии доски, выданные раньше, продолжают видеть старые теги
+	board.Tags = append([]string{}, tags...)
+	return nil
+}
+
+// userFolderLocked возвращает папку, если она принадлежит пользователю
+func (s *MemoryStorage) userFolderLocked(userID int, id int) *models.Folder {
+	folder, ok := s.folders[id]
+	if !ok || folder.UserID != userID {
+		return nil
+	}
+	return folder
+}
+
+// folderDepthLocked уровень вложенности папки: 1 - папка верхнего уровня
+func (s *MemoryStorage) folderDepthLocked(id int) int {
+	depth := 0
+	for ; id != 0; id = s.folders[id].ParentID {
+		depth++
+	}
+	return depth
+}
+
+// subtreeHeightLocked число уровней в поддереве папки, включая ее саму
+func (s *MemoryStorage) subtreeHeightLocked(id int) int {
+	height := 0
+	for _, child := range s.folders {
+		if child.ParentID == id {
+			if h := s.subtreeHeightLocked(child.ID); h > height {
+				height = h
+			}
+		}
+	}
+	return height + 1
+}
@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/alexl/go-fake-api/internal/models"
+)
+
+// Ошибки погашения приглашений
+var (
+	ErrInviteNotFound  = errors.New("invite not found")
+	ErrInviteExpired   = errors.New("invite expired")
+	ErrInviteExhausted = errors.New("invite has no uses left")
+	ErrInviteEmail     = errors.New("invite is issued for another email")
+)
+
+// CreateInvite сохраняет приглашение
+func (s *MemoryStorage) CreateInvite(invite *models.BoardInvite) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.boards[invite.BoardID]; !ok {
+		return errors.New("board not found")
+	}
+	if _, exists := s.invites[invite.Token]; exists {
+		return errors.New("invite token already in use")
+	}
+
+	s.invites[invite.Token] = invite
+	return nil
+}
+
+// GetBoardInvites возвращает приглашения доски в порядке создания
+func (s *MemoryStorage) GetBoardInvites(boardID string) ([]models.BoardInvite, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	invites := []models.BoardInvite{}
+	for _, invite := range s.invites {
+		if invite.BoardID == boardID {
+			invites = append(invites, *invite)
+		}
+	}
+
+	sort.Slice(invites, func(i, j int) bool {
+		if invites[i].CreatedAt.Equal(invites[j].CreatedAt) {
+			return invites[i].Token < invites[j].Token
+		}
+		return invites[i].CreatedAt.Before(invites[j].CreatedAt)
+	})
+
+	return invites, nil
+}
+
+// DeleteInvite отзывает приглашение доски
+func (s *MemoryStorage) DeleteInvite(boardID string, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	invite, ok := s.invites[token]
+	if !ok || invite.BoardID != boardID {
+		return ErrInviteNotFound
+	}
+
+	delete(s.invites, token)
+	return nil
+}
+
+// RedeemInvite погашает приглашение и выдает пользователю доступ с ролью приглашения.
+// Если доступ уже есть, роль не меняется и использование не списывается.
+func (s *MemoryStorage) RedeemInvite(token string, user *models.User, now time.Time) (*models.BoardInvite, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	invite, ok := s.invites[token]
+	if !ok {
+		return nil, ErrInviteNotFound
+	}
+	if invite.Email != "" && invite.Email != user.Email {
+		return nil, ErrInviteEmail
+	}
+	if invite.Expired(now) {
+		return nil, ErrInviteExpired
+	}
+
+	if s.hasBoardAccess(invite.BoardID, user.ID) {
+		result := *invite
+		return &result, nil
+	}
+
+	if invite.Exhausted() {
+		return nil, ErrInviteExhausted
+	}
+
+	invite.Uses++
+	s.addBoardAccess(invite.BoardID, user.ID, invite.Role)
+
+	result := *invite
+	return &result, nil
+}
+
+// ApplyEmailInvites выдает доступ по всем действующим приглашениям на email пользователя.
+// Вызывается при регистрации; примененные приглашения удаляются.
+func (s *MemoryStorage) ApplyEmailInvites(user *models.User, now time.Time) ([]models.BoardInvite, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	applied := []models.BoardInvite{}
+	for token, invite := range s.invites {
+		if invite.Email != user.Email || invite.Expired(now) || invite.Exhausted() {
+			continue
+		}
+		if _, ok := s.boards[invite.BoardID]; !ok {
+			continue
+		}
+
+		invite.Uses++
+		s.addBoardAccess(invite.BoardID, user.ID, invite.Role)
+		applied = append(applied, *invite)
+		delete(s.invites, token)
+	}
+
+	return applied, nil
+}
@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/alexl/go-fake-api/internal/models"
+)
+
+// Ошибки слоев
+var (
+	ErrLayerNotFound = errors.New("layer not found")
+	ErrLayerLocked   = errors.New("layer is locked")
+	ErrUnknownLayer  = errors.New("layer_id does not match any layer of the board")
+	ErrTooManyLayers = fmt.Errorf("board can not contain more than %d layers", models.MaxBoardLayers)
+)
+
+// GetLayers возвращает слои доски снизу вверх
+func (s *MemoryStorage) GetLayers(boardID string) ([]models.Layer, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	board, ok := s.boards[boardID]
+	if !ok {
+		return nil, ErrBoardNotFound
+	}
+	return append([]models.Layer{}, board.Layers...), nil
+}
+
+// CreateLayer добавляет слой поверх остальных и выдает ему ID
+func (s *MemoryStorage) CreateLayer(boardID string, layer *models.Layer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	board, ok := s.boards[boardID]
+	if !ok {
+		return ErrBoardNotFound
+	}
+	if len(board.Layers) >= models.MaxBoardLayers {
+		return ErrTooManyLayers
+	}
+
+	s.layerIDCounter++
+	layer.ID = "layer-" + strconv.Itoa(s.layerIDCounter)
+	board.Layers = append(board.Layers, *layer)
+	return nil
+}
+
+// UpdateLayer меняет переданные поля слоя; position больше последнего индекса
+// кладет слой наверх. Возвращает все слои доски.
+func (s *MemoryStorage) UpdateLayer(boardID string, layerID string, req models.LayerUpdateRequest) ([]models.Layer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	board, ok := s.boards[boardID]
+	if !ok {
+		return nil, ErrBoardNotFound
+	}
+	index := layerIndexLocked(board, layerID)
+	if index < 0 {
+		return nil, ErrLayerNotFound
+	}
+
+	layer := board.Layers[index]
+	if req.Name != nil {
+		layer.Name = *req.Name
+	}
+	if req.Visible != nil {
+		layer.Visible = *req.Visible
+	}
+	if req.Locked != nil {
+		layer.Locked = *req.Locked
+	}
+
+	// Слои копируются, чтобы не менять срезы, уже отданные наружу
+	layers := append([]models.Layer{}, board.Layers[:index]...)
+	layers = append(layers, board.Layers[index+1:]...)
+	position := index
+	if req.Position != nil {
+		position = *req.Position
+	}
+	if position > len(layers) {
+		position = len(layers)
+	}
+	layers = append(layers[:position], append([]models.Layer{layer}, layers[position:]...)...)
+
+	board.Layers = layers
+	return append([]models.Layer{}, layers...), nil
+}
+
+// DeleteLayer удаляет слой, его объекты остаются на доске вне слоев.
+// Закрепленный слой удалить нельзя. Возвращает оставшиеся слои и перенесенные объекты.
+func (s *MemoryStorage) DeleteLayer(boardID string, layerID string) ([]models.Layer, []models.BoardObject, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	board, ok := s.boards[boardID]
+	if !ok {
+		return nil, nil, ErrBoardNotFound
+	}
+	index := layerIndexLocked(board, layerID)
+	if index < 0 {
+		return nil, nil, ErrLayerNotFound
+	}
+	if board.Layers[index].Locked {
+		return nil, nil, fmt.Errorf("%w: %s", ErrLayerLocked, layerID)
+	}
+
+	moved := make(map[string]models.BoardObject)
+	for id, obj := range board.Objects {
+		if obj.LayerID == layerID {
+			obj.LayerID = ""
+			board.Objects[id] = obj
+			moved[id] = obj
+		}
+	}
+
+	layers := append([]models.Layer{}, board.Layers[:index]...)
+	board.Layers = append(layers, board.Layers[index+1:]...)
+	return append([]models.Layer{}, board.Layers...), sortedObjects(moved), nil
+}
+
+// checkLayer проверяет, что в слой можно положить объект: слой есть и не закреплен.
+// Пустой ID - объект вне слоев. Вызывающий держит s.mu.
+func checkLayer(board *models.Board, layerID string) error {
+	if layerID == "" {
+		return nil
+	}
+	layer := layerLocked(board, layerID)
+	if layer == nil {
+		return fmt.Errorf("%w: %s", ErrUnknownLayer, layerID)
+	}
+	if layer.Locked {
+		return fmt.Errorf("%w: %s", ErrLayerLocked, layerID)
+	}
+	return nil
+}
+
+// layerLocked слой доски по ID, nil если его нет
+func layerLocked(board *models.Board, layerID string) *models.Layer {
+	if index := layerIndexLocked(board, layerID); index >= 0 {
+		return &board.Layers[index]
+	}
+	return nil
+}
+
+// layerIndexLocked индекс слоя в списке доски, -1 если его нет
+func layerIndexLocked(board *models.Board, layerID string) int {
+	if layerID == "" {
+		return -1
+	}
+	for i, layer := range board.Layers {
+		if layer.ID == layerID {
+			return i
+		}
+	}
+	return -1
+}
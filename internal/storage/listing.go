@@ -0,0 +1,172 @@
+package storage
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alexl/go-fake-api/internal/models"
+)
+
+// ListBoards возвращает страницу досок по фильтрам, сортировке и курсору или смещению.
+// Порядок однозначен: при равных ключах доски упорядочены по ID.
+func (s *MemoryStorage) ListBoards(q models.BoardQuery) (models.BoardList, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	name := strings.ToLower(q.Name)
+	boards := []models.Board{}
+	for _, board := range s.boards {
+		if q.Template {
+			// Приватный шаблон виден только участникам доски
+			if !board.IsTemplate || (!board.IsPublic && !s.hasBoardAccess(board.ID, q.UserID)) {
+				continue
+			}
+		} else if q.Public {
+			if !board.IsPublic {
+				continue
+			}
+		} else if !s.hasBoardAccess(board.ID, q.UserID) {
+			continue
+		}
+
+		switch q.Ownership {
+		case models.OwnershipOwned:
+			if board.OwnerID != q.UserID {
+				continue
+			}
+		case models.OwnershipShared:
+			if board.OwnerID == q.UserID || !s.hasBoardAccess(board.ID, q.UserID) {
+				continue
+			}
+		}
+
+		if name != "" && !strings.Contains(strings.ToLower(board.Name), name) {
+			continue
+		}
+		if board.Likes < q.MinLikes {
+			continue
+		}
+		if q.CreatedFrom != nil && board.CreatedAt.Before(*q.CreatedFrom) {
+			continue
+		}
+		if q.CreatedTo != nil && !board.CreatedAt.Before(*q.CreatedTo) {
+			continue
+		}
+		if q.Tag != "" && !hasTag(board.Tags, q.Tag) {
+			continue
+		}
+
+		// Личные настройки есть только у списка от имени пользователя
+		listed := *board
+		if q.UserID != 0 {
+			listed.Favorite = s.favorites[q.UserID][board.ID]
+			listed.FolderID = s.boardFolders[q.UserID][board.ID]
+		}
+		if q.Favorite && !listed.Favorite {
+			continue
+		}
+		if q.FolderID != nil && listed.FolderID != *q.FolderID {
+			continue
+		}
+
+		boards = append(boards, listed)
+	}
+
+	less := boardLess(q.Sort, q.Desc)
+	sort.Slice(boards, func(i, j int) bool {
+		return less(boards[i], boards[j])
+	})
+
+	start := q.Offset
+	if q.After != nil {
+		pivot := q.After.Board()
+		start = sort.Search(len(boards), func(i int) bool {
+			return less(pivot, boards[i])
+		})
+	}
+	if start > len(boards) {
+		start = len(boards)
+	}
+	end := start + q.Limit
+	if end > len(boards) {
+		end = len(boards)
+	}
+
+	// Объекты копируются только у досок страницы, пока держим s.mu (см. snapshotLocked)
+	page := boards[start:end]
+	for i := range page {
+		page[i].Objects = copyObjects(page[i].Objects)
+	}
+
+	list := models.BoardList{
+		Boards:  page,
+		Total:   len(boards),
+		HasMore: end < len(boards),
+	}
+	if list.HasMore {
+		list.NextCursor = models.NewBoardCursor(boards[end-1], q.Sort, q.Desc).Encode()
+	}
+	return list, nil
+}
+
+// TouchBoard отмечает время последнего изменения доски
+func (s *MemoryStorage) TouchBoard(boardID string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	board, ok := s.boards[boardID]
+	if !ok {
+		return ErrBoardNotFound
+	}
+	if at.After(board.UpdatedAt) {
+		board.UpdatedAt = at
+	}
+	return nil
+}
+
+// boardLess возвращает сравнение досок для сортировки. Доски с равным ключом
+// упорядочены по ID, чтобы страницы не перекрывались.
+func boardLess(field string, desc bool) func(a, b models.Board) bool {
+	return func(a, b models.Board) bool {
+		var cmp int
+		switch field {
+		case models.BoardSortLikes:
+			cmp = compareInts(a.Likes, b.Likes)
+		case models.BoardSortName:
+			cmp = strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+		case models.BoardSortActivity:
+			cmp = a.UpdatedAt.Compare(b.UpdatedAt)
+		default:
+			cmp = a.CreatedAt.Compare(b.CreatedAt)
+		}
+		if desc {
+			cmp = -cmp
+		}
+		if cmp != 0 {
+			return cmp < 0
+		}
+		return a.ID < b.ID
+	}
+}
+
+// hasTag проверяет, что у доски есть тег
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// compareInts сравнивает два целых: -1, 0 или 1
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
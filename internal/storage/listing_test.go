@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/alexl/go-fake-api/internal/models"
+)
+
+// TestListBoardsConcurrentWrites кодирует списки досок, пока объекты меняются.
+// Гонку на карте объектов ловит go test -race.
+func TestListBoardsConcurrentWrites(t *testing.T) {
+	s := NewMemoryStorage()
+	board := &models.Board{ID: "board-1", Hash: "hash-1", Name: "Board", OwnerID: 1, IsPublic: true}
+	if err := s.CreateBoard(board); err != nil {
+		t.Fatalf("CreateBoard: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			obj := models.BoardObject{ID: fmt.Sprintf("obj-%d", i), Type: models.ObjectRectangle, X: float64(i)}
+			if err := s.UpdateBoardObject("board-1", obj); err != nil {
+				t.Errorf("UpdateBoardObject: %v", err)
+				return
+			}
+		}
+	}()
+
+	lists := map[string]func() (interface{}, error){
+		"ListBoards": func() (interface{}, error) {
+			return s.ListBoards(models.BoardQuery{UserID: 1, Limit: 10})
+		},
+		"GetUserBoards":   func() (interface{}, error) { return s.GetUserBoards(1) },
+		"GetPublicBoards": func() (interface{}, error) { return s.GetPublicBoards() },
+	}
+	for i := 0; i < 100; i++ {
+		for name, list := range lists {
+			result, err := list()
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if _, err := json.Marshal(result); err != nil {
+				t.Fatalf("%s: encode: %v", name, err)
+			}
+		}
+	}
+	wg.Wait()
+
+	list, _ := s.ListBoards(models.BoardQuery{UserID: 1, Limit: 10})
+	if len(list.Boards) != 1 || len(list.Boards[0].Objects) != 200 {
+		t.Fatalf("ListBoards = %d boards, want 1 board with 200 objects", len(list.Boards))
+	}
+
+	// Изменения после выдачи списка не попадают в уже выданную копию
+	s.UpdateBoardObject("board-1", models.BoardObject{ID: "obj-new", Type: models.ObjectRectangle})
+	if _, ok := list.Boards[0].Objects["obj-new"]; ok {
+		t.Errorf("listed board shares objects with storage")
+	}
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"errors"
+
+	"github.com/alexl/go-fake-api/internal/models"
+)
+
+// CreateNotification сохраняет уведомление пользователя и выдает ему ID
+func (s *MemoryStorage) CreateNotification(notification *models.Notification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[notification.UserID]; !ok {
+		return errors.New("user not found")
+	}
+
+	s.notificationIDCounter++
+	notification.ID = s.notificationIDCounter
+	stored := *notification
+	s.notifications[notification.UserID] = append(s.notifications[notification.UserID], &stored)
+	return nil
+}
+
+// GetNotifications возвращает до limit уведомлений пользователя с ID меньше before
+// (before = 0 - с самого нового), от новых к старым, и признак более старых уведомлений
+func (s *MemoryStorage) GetNotifications(userID int, unreadOnly bool, before int, limit int) ([]models.Notification, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	page := []models.Notification{}
+	notifications := s.notifications[userID]
+	for i := len(notifications) - 1; i >= 0; i-- {
+		notification := notifications[i]
+		if (before > 0 && notification.ID >= before) || (unreadOnly && notification.Read) {
+			continue
+		}
+		if len(page) == limit {
+			return page, true, nil
+		}
+		page = append(page, *notification)
+	}
+	return page, false, nil
+}
+
+// CountUnreadNotifications возвращает число непрочитанных уведомлений пользователя
+func (s *MemoryStorage) CountUnreadNotifications(userID int) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.countUnreadLocked(userID), nil
+}
+
+// MarkNotificationsRead отмечает уведомления пользователя прочитанными, ids = nil - все.
+// Возвращает ID уведомлений, которые были непрочитанными, и оставшееся число непрочитанных.
+// Чужие и неизвестные ID пропускаются.
+func (s *MemoryStorage) MarkNotificationsRead(userID int, ids []int) ([]int, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var wanted map[int]bool
+	if ids != nil {
+		wanted = make(map[int]bool, len(ids))
+		for _, id := range ids {
+			wanted[id] = true
+		}
+	}
+
+	marked := []int{}
+	for _, notification := range s.notifications[userID] {
+		if notification.Read || (wanted != nil && !wanted[notification.ID]) {
+			continue
+		}
+		notification.Read = true
+		marked = append(marked, notification.ID)
+	}
+	return marked, s.countUnreadLocked(userID), nil
+}
+
+// countUnreadLocked считает непрочитанные уведомления, вызывающий держит s.mu
+func (s *MemoryStorage) countUnreadLocked(userID int) int {
+	unread := 0
+	for _, notification := range s.notifications[userID] {
+		if !notification.Read {
+			unread++
+		}
+	}
+	return unread
+}
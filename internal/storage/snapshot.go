@@ -0,0 +1,220 @@
+package storage
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/alexl/go-fake-api/internal/models"
+)
+
+// ErrSnapshotNotFound снимок не найден
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+// CreateSnapshot сохраняет копию текущих объектов доски и выдает снимку ID.
+// Захваты объектов в снимок не попадают.
+func (s *MemoryStorage) CreateSnapshot(snapshot *models.Snapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	board, ok := s.boards[snapshot.BoardID]
+	if !ok {
+		return ErrBoardNotFound
+	}
+
+	objects := make(map[string]models.BoardObject, len(board.Objects))
+	for id, obj := range board.Objects {
+		obj.FocusedBy = nil
+		obj.FocusedAt = nil
+		obj.OwnerName = ""
+		objects[id] = obj
+	}
+
+	s.snapshotIDCounter++
+	snapshot.ID = s.snapshotIDCounter
+	snapshot.ObjectCount = len(objects)
+	stored := *snapshot
+	stored.Objects = objects
+	s.snapshots[snapshot.BoardID] = append(s.snapshots[snapshot.BoardID], &stored)
+	return nil
+}
+
+// GetSnapshots возвращает снимки доски от новых к старым, без объектов
+func (s *MemoryStorage) GetSnapshots(boardID string) ([]models.Snapshot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.boards[boardID]; !ok {
+		return nil, ErrBoardNotFound
+	}
+
+	snapshots := make([]models.Snapshot, 0, len(s.snapshots[boardID]))
+	for i := len(s.snapshots[boardID]) - 1; i >= 0; i-- {
+		snapshot := *s.snapshots[boardID][i]
+		snapshot.Objects = nil
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, nil
+}
+
+// GetSnapshot возвращает снимок доски вместе с объектами
+func (s *MemoryStorage) GetSnapshot(boardID string, id int) (models.Snapshot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stored := s.snapshotLocked(boardID, id)
+	if stored == nil {
+		return models.Snapshot{}, ErrSnapshotNotFound
+	}
+
+	snapshot := *stored
+	snapshot.Objects = make(map[string]models.BoardObject, len(stored.Objects))
+	for objectID, obj := range stored.Objects {
+		snapshot.Objects[objectID] = obj
+	}
+	return snapshot, nil
+}
+
+// RestoreSnapshot заменяет объекты доски объектами снимка, в том числе захваченные
+// другими пользователями. Закрепленные объекты и объекты закрепленных слоев остаются
+// как есть. Возвращает новые объекты по возрастанию ID.
+func (s *MemoryStorage) RestoreSnapshot(boardID string, id int) ([]models.BoardObject, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	board, ok := s.boards[boardID]
+	if !ok {
+		return nil, ErrBoardNotFound
+	}
+	snapshot := s.snapshotLocked(boardID, id)
+	if snapshot == nil {
+		return nil, ErrSnapshotNotFound
+	}
+
+	// Закрепленные объекты и объекты закрепленных слоев восстановление не трогает:
+	// они остаются как есть, а их версии из снимка отбрасываются
+	objects := make(map[string]models.BoardObject, len(snapshot.Objects))
+	for id, obj := range board.Objects {
+		if pinnedLocked(board, obj) {
+			objects[id] = obj
+		}
+	}
+	for id, obj := range snapshot.Objects {
+		if _, kept := objects[id]; kept {
+			continue
+		}
+		// Закрепление задает владелец для текущей доски, значения из снимка не применяются
+		obj.Locked = false
+		// Слои в снимок не входят: удаленный слой сбрасывается, на закрепленный слой объект не попадает
+		layer := layerLocked(board, obj.LayerID)
+		if layer == nil {
+			obj.LayerID = ""
+		} else if layer.Locked {
+			continue
+		}
+		objects[id] = obj
+	}
+	fixRestoredConnectors(objects)
+	fixRestoredGroups(objects)
+
+	board.Objects = objects
+	return sortedObjects(objects), nil
+}
+
+// pinnedLocked объект закреплен сам или лежит на закрепленном слое
+func pinnedLocked(board *models.Board, obj models.BoardObject) bool {
+	if obj.Locked {
+		return true
+	}
+	layer := layerLocked(board, obj.LayerID)
+	return layer != nil && layer.Locked
+}
+
+// fixRestoredConnectors удаляет соединители, чьих концов нет среди восстановленных объектов,
+// и проводит остальные заново: оставленные на месте концы могли сдвинуться после снимка
+func fixRestoredConnectors(objects map[string]models.BoardObject) {
+	for id, conn := range objects {
+		if conn.Type != models.ObjectConnector {
+			continue
+		}
+		from, okFrom := objects[conn.From]
+		to, okTo := objects[conn.To]
+		if !okFrom || !okTo || from.Type == models.ObjectConnector || to.Type == models.ObjectConnector {
+			delete(objects, id)
+			continue
+		}
+		objects[id] = routeConnector(conn, from, to)
+	}
+}
+
+// fixRestoredGroups согласует group_id и children после смешивания объектов снимка
+// с оставленными на месте: ссылка на отсутствующую группу или замыкающая цикл
+// сбрасывается, а children группы собираются из объектов, ссылающихся на нее
+func fixRestoredGroups(objects map[string]models.BoardObject) {
+	ids := make([]string, 0, len(objects))
+	for id := range objects {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		obj := objects[id]
+		if obj.GroupID == "" {
+			continue
+		}
+		if parent, ok := objects[obj.GroupID]; !ok || parent.Type != models.ObjectGroup {
+			obj.GroupID = ""
+			objects[id] = obj
+		}
+	}
+	for _, id := range ids {
+		seen := map[string]bool{id: true}
+		for parentID := objects[id].GroupID; parentID != ""; parentID = objects[parentID].GroupID {
+			if seen[parentID] {
+				obj := objects[id]
+				obj.GroupID = ""
+				objects[id] = obj
+				break
+			}
+			seen[parentID] = true
+		}
+	}
+
+	members := make(map[string][]string)
+	for _, id := range ids {
+		if groupID := objects[id].GroupID; groupID != "" {
+			members[groupID] = append(members[groupID], id)
+		}
+	}
+	for _, id := range ids {
+		group := objects[id]
+		if group.Type != models.ObjectGroup {
+			continue
+		}
+		// Порядок детей из объекта группы сохраняется, недостающие дописываются по ID
+		children := make([]string, 0, len(members[id]))
+		listed := make(map[string]bool, len(group.Children))
+		for _, childID := range group.Children {
+			if child, ok := objects[childID]; ok && child.GroupID == id && !listed[childID] {
+				children = append(children, childID)
+				listed[childID] = true
+			}
+		}
+		for _, childID := range members[id] {
+			if !listed[childID] {
+				children = append(children, childID)
+			}
+		}
+		group.Children = children
+		objects[id] = group
+	}
+}
+
+// snapshotLocked ищет снимок доски, nil если его нет
+func (s *MemoryStorage) snapshotLocked(boardID string, id int) *models.Snapshot {
+	for _, snapshot := range s.snapshots[boardID] {
+		if snapshot.ID == id {
+			return snapshot
+		}
+	}
+	return nil
+}
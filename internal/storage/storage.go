@@ -2,6 +2,7 @@ package storage
 
 import (
 	"sync"
+	"time"
 
 	"github.com/alexl/go-fake-api/internal/models"
 )
@@ -12,31 +13,118 @@ type Storage interface {
 	CreateUser(user *models.User) error
 	GetUserByEmail(email string) (*models.User, error)
 	GetUserByToken(token string) (*models.User, error)
-	UpdateUserToken(userID int, token string) error
+	UpdateUserToken(userID int, token string, expiresAt time.Time) error
 
 	// Boards
 	CreateBoard(board *models.Board) error
 	GetBoardByID(id string) (*models.Board, error)
 	GetBoardByHash(hash string) (*models.Board, error)
+	UpdateBoardHash(boardID string, hash string) error
+	SetBoardLinkSharing(boardID string, enabled bool) error
+	SetBoardTemplate(boardID string, enabled bool) error
 	GetUserBoards(userID int) ([]models.Board, error)
 	GetPublicBoards() ([]models.Board, error)
+	ListBoards(q models.BoardQuery) (models.BoardList, error)
+	TouchBoard(boardID string, at time.Time) error
+	GetBoardObject(boardID string, objectID string) (models.BoardObject, error)
+	GetBoardObjects(boardID string) ([]models.BoardObject, error)
 	UpdateBoardObject(boardID string, obj models.BoardObject) error
+	SetObjectFocus(boardID string, objectID string, userID int, userName string, focus bool, now time.Time) (models.BoardObject, bool, error)
 	DeleteBoardObject(boardID string, objectID string) error
-	AddBoardAccess(boardID string, userID int) error
+	ApplyObjectsBatch(boardID string, userID int, updates []models.BoardObject, deletes []string) (models.ObjectsBatch, error)
+	CreateBoardObject(boardID string, userID int, obj models.BoardObject) (models.ObjectsBatch, error)
+	ReorderObjects(boardID string, userID int, ids []string, action string) (map[string]int, error)
+	GroupObjects(boardID string, userID int, groupID string, children []string) ([]models.BoardObject, error)
+	UngroupObjects(boardID string, userID int, groupID string) ([]models.BoardObject, error)
+	SetObjectLocked(boardID string, objectID string, locked bool) (models.BoardObject, error)
+	AddBoardAccess(boardID string, userID int, role string) error
+	RemoveBoardAccess(boardID string, userID int) error
+	GetBoardMembers(boardID string) ([]models.BoardAccess, error)
 	HasBoardAccess(boardID string, userID int) (bool, error)
-	LikeBoard(boardID string, userID int) error
+	GetBoardRole(boardID string, userID int) (string, error)
+	LikeBoard(boardID string, userID int) (bool, error)
+
+	// Invites
+	CreateInvite(invite *models.BoardInvite) error
+	GetBoardInvites(boardID string) ([]models.BoardInvite, error)
+	DeleteInvite(boardID string, token string) error
+	RedeemInvite(token string, user *models.User, now time.Time) (*models.BoardInvite, error)
+	ApplyEmailInvites(user *models.User, now time.Time) ([]models.BoardInvite, error)
+
+	// Chat
+	CreateChatMessage(msg *models.ChatMessage) error
+	GetChatMessage(boardID string, id int) (models.ChatMessage, error)
+	UpdateChatMessage(boardID string, id int, text string, mentions []int, editedAt time.Time) (models.ChatMessage, error)
+	DeleteChatMessage(boardID string, id int) error
+	GetChatMessages(boardID string, before int, limit int) ([]models.ChatMessage, bool, error)
+
+	// Comments
+	CreateComment(comment *models.Comment) error
+	GetComment(boardID string, id int) (models.Comment, error)
+	UpdateComment(boardID string, id int, text string, mentions []int, editedAt time.Time) (models.Comment, error)
+	DeleteComment(boardID string, id int) ([]int, error)
+	GetCommentThreads(boardID string, objectID string, before int, limit int) ([]models.CommentThread, bool, error)
+
+	// Layers
+	GetLayers(boardID string) ([]models.Layer, error)
+	CreateLayer(boardID string, layer *models.Layer) error
+	UpdateLayer(boardID string, layerID string, req models.LayerUpdateRequest) ([]models.Layer, error)
+	DeleteLayer(boardID string, layerID string) ([]models.Layer, []models.BoardObject, error)
+
+	// Snapshots
+	CreateSnapshot(snapshot *models.Snapshot) error
+	GetSnapshots(boardID string) ([]models.Snapshot, error)
+	GetSnapshot(boardID string, id int) (models.Snapshot, error)
+	RestoreSnapshot(boardID string, id int) ([]models.BoardObject, error)
+
+	// Assets
+	CreateAsset(asset *models.Asset) error
+	GetAsset(id string) (models.Asset, error)
+	GetBoardAssets(boardID string) ([]models.Asset, error)
+	CollectAssets(before time.Time) []models.Asset
+
+	// Folders, tags and favorites
+	CreateFolder(folder *models.Folder) error
+	GetFolders(userID int) ([]models.Folder, error)
+	UpdateFolder(userID int, id int, name *string, parentID *int) (models.Folder, error)
+	DeleteFolder(userID int, id int) error
+	SetBoardFolder(userID int, boardID string, folderID int) error
+	SetBoardFavorite(userID int, boardID string, favorite bool) error
+	SetBoardTags(boardID string, tags []string) error
+
+	// Notifications
+	CreateNotification(notification *models.Notification) error
+	GetNotifications(userID int, unreadOnly bool, before int, limit int) ([]models.Notification, bool, error)
+	CountUnreadNotifications(userID int) (int, error)
+	MarkNotificationsRead(userID int, ids []int) ([]int, int, error)
 }
 
 // MemoryStorage хранилище в памяти
 type MemoryStorage struct {
-	users         map[int]*models.User
-	usersByEmail  map[string]*models.User
-	usersByToken  map[string]*models.User
-	boards        map[string]*models.Board
-	boardAccess   map[string][]int        // boardID -> []userID
-	boardLikes    map[string]map[int]bool // boardID -> userID -> true
-	userIDCounter int
-	mu            sync.RWMutex
+	users                 map[int]*models.User
+	usersByEmail          map[string]*models.User
+	usersByToken          map[string]*models.User
+	boards                map[string]*models.Board
+	boardsByHash          map[string]*models.Board
+	boardAccess           map[string][]int                 // boardID -> []userID
+	boardRoles            map[string]map[int]string        // boardID -> userID -> role
+	boardLikes            map[string]map[int]bool          // boardID -> userID -> true
+	invites               map[string]*models.BoardInvite   // token -> invite
+	chatMessages          map[string][]*models.ChatMessage // boardID -> сообщения в порядке создания
+	comments              map[string][]*models.Comment     // boardID -> комментарии в порядке создания
+	notifications         map[int][]*models.Notification   // userID -> уведомления в порядке создания
+	folders               map[int]*models.Folder           // folderID -> папка
+	boardFolders          map[int]map[string]int           // userID -> boardID -> folderID
+	favorites             map[int]map[string]bool          // userID -> boardID -> true
+	snapshots             map[string][]*models.Snapshot    // boardID -> снимки в порядке создания
+	assets                map[string]*models.Asset         // assetID -> изображение
+	userIDCounter         int
+	messageIDCounter      int
+	notificationIDCounter int
+	folderIDCounter       int
+	snapshotIDCounter     int
+	layerIDCounter        int
+	mu                    sync.RWMutex
 }
 
 // NewMemoryStorage создает новое хранилище в памяти
@@ -46,8 +134,19 @@ func NewMemoryStorage() *MemoryStorage {
 		usersByEmail:  make(map[string]*models.User),
 		usersByToken:  make(map[string]*models.User),
 		boards:        make(map[string]*models.Board),
+		boardsByHash:  make(map[string]*models.Board),
 		boardAccess:   make(map[string][]int),
+		boardRoles:    make(map[string]map[int]string),
 		boardLikes:    make(map[string]map[int]bool),
+		invites:       make(map[string]*models.BoardInvite),
+		chatMessages:  make(map[string][]*models.ChatMessage),
+		comments:      make(map[string][]*models.Comment),
+		notifications: make(map[int][]*models.Notification),
+		folders:       make(map[int]*models.Folder),
+		boardFolders:  make(map[int]map[string]int),
+		favorites:     make(map[int]map[string]bool),
+		snapshots:     make(map[string][]*models.Snapshot),
+		assets:        make(map[string]*models.Asset),
 		userIDCounter: 1,
 	}
 }
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"errors"
+	"time"
 
 	"github.com/alexl/go-fake-api/internal/models"
 )
@@ -51,7 +52,7 @@ func (s *MemoryStorage) GetUserByToken(token string) (*models.User, error) {
 }
 
 // UpdateUserToken обновляет токен пользователя
-func (s *MemoryStorage) UpdateUserToken(userID int, token string) error {
+func (s *MemoryStorage) UpdateUserToken(userID int, token string, expiresAt time.Time) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -67,6 +68,7 @@ func (s *MemoryStorage) UpdateUserToken(userID int, token string) error {
 
 	// Устанавливаем новый токен
 	user.Token = token
+	user.TokenExpiresAt = expiresAt
 	if token != "" {
 		s.usersByToken[token] = user
 	}
@@ -0,0 +1,189 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alexl/go-fake-api/internal/models"
+)
+
+// Форматы цвета CSS: #rgb, #rgba, #rrggbb, #rrggbbaa и функции rgb(), rgba(), hsl(), hsla()
+var (
+	hexColorRegex  = regexp.MustCompile(`^#([0-9a-fA-F]{3,4}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})$`)
+	funcColorRegex = regexp.MustCompile(`^(?i)(rgba?|hsla?)\(\s*[-+0-9.]+(deg|%)?(\s*[,\s/]\s*[-+0-9.]+%?){2,3}\s*\)$`)
+)
+
+// assetPathRegex путь к загруженному изображению или его варианту, с базовым путем API или без
+var assetPathRegex = regexp.MustCompile(`^(/[0-9A-Za-z._-]+)*/assets/[0-9A-Za-z]+(/[a-z]+)?$`)
+
+// cssColorNames именованные цвета CSS
+var cssColorNames = map[string]bool{}
+
+func init() {
+	for _, name := range strings.Fields(`
+		transparent currentcolor
+		aliceblue antiquewhite aqua aquamarine azure beige bisque black blanchedalmond blue
+		blueviolet brown burlywood cadetblue chartreuse chocolate coral cornflowerblue cornsilk
+		crimson cyan darkblue darkcyan darkgoldenrod darkgray darkgreen darkgrey darkkhaki
+		darkmagenta darkolivegreen darkorange darkorchid darkred darksalmon darkseagreen
+		darkslateblue darkslategray darkslategrey darkturquoise darkviolet deeppink deepskyblue
+		dimgray dimgrey dodgerblue firebrick floralwhite forestgreen fuchsia gainsboro ghostwhite
+		gold goldenrod gray green greenyellow grey honeydew hotpink indianred indigo ivory khaki
+		lavender lavenderblush lawngreen lemonchiffon lightblue lightcoral lightcyan
+		lightgoldenrodyellow lightgray lightgreen lightgrey lightpink lightsalmon lightseagreen
+		lightskyblue lightslategray lightslategrey lightsteelblue lightyellow lime limegreen linen
+		magenta maroon mediumaquamarine mediumblue mediumorchid mediumpurple mediumseagreen
+		mediumslateblue mediumspringgreen mediumturquoise mediumvioletred midnightblue mintcream
+		mistyrose moccasin navajowhite navy oldlace olive olivedrab orange orangered orchid
+		palegoldenrod palegreen paleturquoise palevioletred papayawhip peachpuff peru pink plum
+		powderblue purple rebeccapurple red rosybrown royalblue saddlebrown salmon sandybrown
+		seagreen seashell sienna silver skyblue slateblue slategray slategrey snow springgreen
+		steelblue tan teal thistle tomato turquoise violet wheat white whitesmoke yellow yellowgreen`) {
+		cssColorNames[name] = true
+	}
+}
+
+// IsCSSColor проверяет, что строка - цвет CSS: hex, rgb(a), hsl(a) или именованный
+func IsCSSColor(color string) bool {
+	return hexColorRegex.MatchString(color) ||
+		funcColorRegex.MatchString(color) ||
+		cssColorNames[strings.ToLower(color)]
+}
+
+// ValidateObject валидирует объект доски по правилам его типа.
+// Ключи ошибок - JSON-имена полей объекта. Поля z, group_id, children и locked
+// сервер заполняет сам и не проверяет.
+func ValidateObject(obj models.BoardObject) map[string][]string {
+	errors := make(map[string][]string)
+	add := func(field, message string) {
+		errors[field] = append(errors[field], message)
+	}
+
+	if obj.ID == "" {
+		add("id", "field id can not be blank")
+	} else if len(obj.ID) > models.MaxObjectIDLen {
+		add("id", fmt.Sprintf("id must be at most %d characters", models.MaxObjectIDLen))
+	}
+
+	// Координаты и размеры общие для всех типов
+	for field, value := range map[string]float64{"x": obj.X, "y": obj.Y} {
+		if !isFinite(value) || math.Abs(value) > models.MaxCoordinate {
+			add(field, fmt.Sprintf("%s must be a number between %.0f and %.0f", field, -models.MaxCoordinate, models.MaxCoordinate))
+		}
+	}
+	for field, value := range map[string]float64{"width": obj.Width, "height": obj.Height} {
+		if !isFinite(value) || value < 0 || value > models.MaxObjectSize {
+			add(field, fmt.Sprintf("%s must be a number between 0 and %.0f", field, models.MaxObjectSize))
+		}
+	}
+	if !isFinite(obj.Rotation) || math.Abs(obj.Rotation) > 360 {
+		add("rotation", "rotation must be a number between -360 and 360")
+	}
+
+	if obj.Color != "" && !IsCSSColor(obj.Color) {
+		add("color", "color must be a valid CSS color")
+	}
+	if utf8.RuneCountInString(obj.Content) > models.MaxObjectContent {
+		add("content", fmt.Sprintf("content must be at most %d characters", models.MaxObjectContent))
+	}
+	if obj.Type != models.ObjectText && obj.FontSize != 0 {
+		add("font_size", "font_size is only allowed for text")
+	}
+	if !hasPoints(obj.Type) && obj.Points != nil {
+		add("points", "points are only allowed for line, path and connector")
+	}
+	if obj.Type != models.ObjectConnector && (obj.From != "" || obj.To != "") {
+		add("from", "from and to are only allowed for connector")
+	}
+
+	switch obj.Type {
+	case models.ObjectText:
+		if obj.Width <= 0 {
+			add("width", "text must have a positive width")
+		}
+		if !isFinite(obj.FontSize) || obj.FontSize < models.MinFontSize || obj.FontSize > models.MaxFontSize {
+			add("font_size", fmt.Sprintf("font_size is required and must be between %d and %d", models.MinFontSize, models.MaxFontSize))
+		}
+
+	case models.ObjectImage:
+		if !isImageURL(obj.Content) {
+			add("content", fmt.Sprintf("image content must be an http(s) URL or an uploaded asset path of at most %d characters", models.MaxImageURLLen))
+		}
+		if obj.Width <= 0 || obj.Height <= 0 {
+			add("width", "image must have a positive width and height")
+		}
+
+	case models.ObjectRectangle, models.ObjectCircle:
+		if obj.Width <= 0 || obj.Height <= 0 {
+			add("width", obj.Type+" must have a positive width and height")
+		}
+
+	case models.ObjectLine, models.ObjectPath:
+		limit := models.MaxLinePoints
+		if obj.Type == models.ObjectPath {
+			limit = models.MaxPathInput
+		}
+		if len(obj.Points) < 4 || len(obj.Points)%2 != 0 {
+			add("points", "points must contain at least two x, y pairs")
+		} else if len(obj.Points) > limit*2 {
+			add("points", fmt.Sprintf("%s can have at most %d points", obj.Type, limit))
+		}
+		for _, value := range obj.Points {
+			if !isFinite(value) || math.Abs(value) > models.MaxCoordinate {
+				add("points", "points must be finite numbers within the canvas")
+				break
+			}
+		}
+
+	case models.ObjectConnector:
+		// Геометрию соединителя считает сервер, проверяются только ссылки на концы
+		for field, id := range map[string]string{"from": obj.From, "to": obj.To} {
+			switch {
+			case id == "":
+				add(field, "connector must have from and to object ids")
+			case len(id) > models.MaxObjectIDLen:
+				add(field, fmt.Sprintf("%s must be at most %d characters", field, models.MaxObjectIDLen))
+			case id == obj.ID:
+				add(field, "connector can not connect to itself")
+			}
+		}
+		if obj.From != "" && obj.From == obj.To {
+			add("to", "connector must connect two different objects")
+		}
+
+	case models.ObjectGroup:
+		// Рамка группы может быть пустой, состав группы меняют только операции группировки
+
+	default:
+		add("type", "type must be one of text, image, rectangle, circle, line, path, connector, group")
+	}
+
+	return errors
+}
+
+// hasPoints типы объектов с полем points
+func hasPoints(objType string) bool {
+	return objType == models.ObjectLine || objType == models.ObjectPath || objType == models.ObjectConnector
+}
+
+// isFinite проверяет, что число не NaN и не бесконечность
+func isFinite(value float64) bool {
+	return !math.IsNaN(value) && !math.IsInf(value, 0)
+}
+
+// isImageURL проверяет, что строка - абсолютный http(s) URL или путь к загруженному
+// изображению допустимой длины
+func isImageURL(value string) bool {
+	if value == "" || len(value) > models.MaxImageURLLen {
+		return false
+	}
+	if assetPathRegex.MatchString(value) {
+		return true
+	}
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
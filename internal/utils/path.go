@@ -0,0 +1,67 @@
+package utils
+
+import "math"
+
+// SimplifyPath упрощает ломаную из плоского списка [x1, y1, x2, y2, ...] алгоритмом
+// Рамера-Дугласа-Пекера: отбрасывает точки, отклоняющиеся от упрощенной линии не больше
+// чем на tolerance. Если точек остается больше max, допуск удваивается.
+// Первая и последняя точки сохраняются всегда.
+func SimplifyPath(points []float64, tolerance float64, max int) []float64 {
+	if len(points) < 6 {
+		return points
+	}
+	for {
+		simplified := simplifyRDP(points, tolerance)
+		if len(simplified)/2 <= max || len(simplified) <= 4 {
+			return simplified
+		}
+		tolerance *= 2
+	}
+}
+
+// simplifyRDP один проход упрощения с заданным допуском
+func simplifyRDP(points []float64, tolerance float64) []float64 {
+	n := len(points) / 2
+	keep := make([]bool, n)
+	keep[0], keep[n-1] = true, true
+
+	// Стек отрезков вместо рекурсии: у длинных рисунков глубина может быть большой
+	stack := [][2]int{{0, n - 1}}
+	for len(stack) > 0 {
+		span := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		first, last := span[0], span[1]
+
+		farthest, distance := -1, tolerance
+		for i := first + 1; i < last; i++ {
+			d := segmentDistance(points[2*i], points[2*i+1],
+				points[2*first], points[2*first+1], points[2*last], points[2*last+1])
+			if d > distance {
+				farthest, distance = i, d
+			}
+		}
+		if farthest >= 0 {
+			keep[farthest] = true
+			stack = append(stack, [2]int{first, farthest}, [2]int{farthest, last})
+		}
+	}
+
+	simplified := make([]float64, 0, len(points))
+	for i, kept := range keep {
+		if kept {
+			simplified = append(simplified, points[2*i], points[2*i+1])
+		}
+	}
+	return simplified
+}
+
+// segmentDistance расстояние от точки (px, py) до отрезка (ax, ay)-(bx, by)
+func segmentDistance(px, py, ax, ay, bx, by float64) float64 {
+	dx, dy := bx-ax, by-ay
+	length := dx*dx + dy*dy
+	if length == 0 {
+		return math.Hypot(px-ax, py-ay)
+	}
+	t := math.Max(0, math.Min(1, ((px-ax)*dx+(py-ay)*dy)/length))
+	return math.Hypot(px-(ax+t*dx), py-(ay+t*dy))
+}
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestSimplifyPath(t *testing.T) {
+	tests := []struct {
+		name      string
+		points    []float64
+		tolerance float64
+		max       int
+		want      []float64
+	}{
+		{"too short", []float64{0, 0, 5, 5}, 1, 100, []float64{0, 0, 5, 5}},
+		{"collinear points dropped", []float64{0, 0, 1, 0, 2, 0, 3, 0}, 0.5, 100, []float64{0, 0, 3, 0}},
+		{"small deviation dropped", []float64{0, 0, 5, 0.4, 10, 0}, 0.5, 100, []float64{0, 0, 10, 0}},
+		{"corner kept", []float64{0, 0, 5, 5, 10, 0}, 0.5, 100, []float64{0, 0, 5, 5, 10, 0}},
+		{"zigzag kept", []float64{0, 0, 1, 3, 2, 0, 3, 3, 4, 0}, 0.5, 100, []float64{0, 0, 1, 3, 2, 0, 3, 3, 4, 0}},
+		{"closed path", []float64{0, 0, 10, 0, 10, 10, 0, 0}, 0.5, 100, []float64{0, 0, 10, 0, 10, 10, 0, 0}},
+		{"tolerance grows to fit max", []float64{0, 0, 1, 3, 2, 0, 3, 3, 4, 0}, 0.5, 2, []float64{0, 0, 4, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SimplifyPath(tt.points, tt.tolerance, tt.max)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SimplifyPath(%v, %v, %d) = %v, want %v", tt.points, tt.tolerance, tt.max, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSimplifyPathLimit(t *testing.T) {
+	// Синусоида из 10000 точек: результат укладывается в max и сохраняет концы
+	points := make([]float64, 0, 20000)
+	for i := 0; i < 10000; i++ {
+		x := float64(i)
+		points = append(points, x, 100*math.Sin(x/50))
+	}
+
+	got := SimplifyPath(points, 0.1, 200)
+	if len(got)/2 > 200 {
+		t.Fatalf("SimplifyPath kept %d points, want at most 200", len(got)/2)
+	}
+	if len(got)%2 != 0 {
+		t.Fatalf("SimplifyPath returned odd number of coordinates: %d", len(got))
+	}
+	if got[0] != points[0] || got[1] != points[1] || got[len(got)-2] != points[len(points)-2] || got[len(got)-1] != points[len(points)-1] {
+		t.Errorf("SimplifyPath moved the endpoints: %v ... %v", got[:2], got[len(got)-2:])
+	}
+}
+
+func TestSegmentDistance(t *testing.T) {
+	tests := []struct {
+		name                   string
+		px, py, ax, ay, bx, by float64
+		want                   float64
+	}{
+		{"projection inside", 5, 3, 0, 0, 10, 0, 3},
+		{"before start", -3, 4, 0, 0, 10, 0, 5},
+		{"after end", 13, 4, 0, 0, 10, 0, 5},
+		{"degenerate segment", 3, 4, 0, 0, 0, 0, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := segmentDistance(tt.px, tt.py, tt.ax, tt.ay, tt.bx, tt.by)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("segmentDistance = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
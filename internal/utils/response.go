@@ -70,6 +70,18 @@ func SendSuccess(w http.ResponseWriter, statusCode int, message string, data int
 	RespondWithJSON(w, statusCode, response)
 }
 
+// SendList отправляет успешный ответ со страницей списка и ее метаданными
+func SendList(w http.ResponseWriter, statusCode int, message string, data interface{}, meta interface{}) {
+	response := map[string]interface{}{
+		"data": data,
+		"meta": meta,
+	}
+	if message != "" {
+		response["message"] = message
+	}
+	RespondWithJSON(w, statusCode, response)
+}
+
 // SendError отправляет ответ с ошибкой
 func SendError(w http.ResponseWriter, statusCode int, message string, errors map[string][]string) {
 	if errors != nil {
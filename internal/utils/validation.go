@@ -3,6 +3,7 @@ package utils
 import (
 	"regexp"
 	"strings"
+	"time"
 	"unicode"
 
 	"github.com/alexl/go-fake-api/internal/models"
@@ -53,6 +54,53 @@ func ValidateAuthorization(req models.AuthorizationRequest) map[string][]string
 	return errors
 }
 
+// ValidateShare валидирует запрос на предоставление доступа
+func ValidateShare(req models.BoardShareRequest) map[string][]string {
+	errors := make(map[string][]string)
+
+	if req.Email == "" {
+		errors["email"] = append(errors["email"], "field email can not be blank")
+	} else if !isValidEmail(req.Email) {
+		errors["email"] = append(errors["email"], "invalid email format")
+	}
+
+	if !isInviteRole(req.Role) {
+		errors["role"] = append(errors["role"], "role must be editor or viewer")
+	}
+
+	return errors
+}
+
+// ValidateInvite валидирует запрос на создание приглашения
+func ValidateInvite(req models.InviteCreateRequest) map[string][]string {
+	errors := make(map[string][]string)
+
+	if !isInviteRole(req.Role) {
+		errors["role"] = append(errors["role"], "role must be editor or viewer")
+	}
+
+	if req.Email != "" && !isValidEmail(req.Email) {
+		errors["email"] = append(errors["email"], "invalid email format")
+	}
+
+	if req.ExpiresIn != "" {
+		if d, err := time.ParseDuration(req.ExpiresIn); err != nil || d <= 0 {
+			errors["expires_in"] = append(errors["expires_in"], "expires_in must be a positive duration like 72h")
+		}
+	}
+
+	if req.MaxUses < 0 {
+		errors["max_uses"] = append(errors["max_uses"], "max_uses can not be negative")
+	}
+
+	return errors
+}
+
+// isInviteRole проверяет, что роль можно выдать приглашением
+func isInviteRole(role string) bool {
+	return role == models.RoleEditor || role == models.RoleViewer
+}
+
 // isLatin проверяет, содержит ли строка только латинские буквы
 func isLatin(s string) bool {
 	for _, r := range s {
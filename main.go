@@ -1,14 +1,29 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"flag"
+	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/alexl/go-fake-api/internal/api"
+	"github.com/alexl/go-fake-api/internal/assets"
+	"github.com/alexl/go-fake-api/internal/broker"
+	"github.com/alexl/go-fake-api/internal/clock"
+	"github.com/alexl/go-fake-api/internal/generator"
+	"github.com/alexl/go-fake-api/internal/idgen"
 	"github.com/alexl/go-fake-api/internal/middleware"
+	"github.com/alexl/go-fake-api/internal/models"
+	"github.com/alexl/go-fake-api/internal/search"
 	"github.com/alexl/go-fake-api/internal/storage"
 	"github.com/gorilla/mux"
 	_ "embed"
@@ -18,13 +33,81 @@ import (
 var documentation []byte
 
 func main() {
+	// Подкоманда generate печатает сгенерированные данные и завершается
+	if len(os.Args) > 1 && os.Args[1] == "generate" {
+		runGenerate(os.Args[2:])
+		return
+	}
+
+	// Подкоманда hub-bench замеряет пропускную способность рассылки
+	if len(os.Args) > 1 && os.Args[1] == "hub-bench" {
+		runHubBench(os.Args[2:])
+		return
+	}
+
+	// Подкоманда broker запускает встроенный pub/sub сервер для нескольких реплик
+	if len(os.Args) > 1 && os.Args[1] == "broker" {
+		runBroker(os.Args[2:])
+		return
+	}
+
 	// Парсинг аргументов командной строки
 	var baseURL string
 	var port string
+	var deterministic bool
+	var seed int64
+	var clockStart string
+	var hashLength int
+	var brokerURL string
+	var brokerPrefix string
+	var assetsDir string
+	var assetGCInterval time.Duration
+	var assetGCGrace time.Duration
+	hubConfig := api.DefaultHubConfig()
 	flag.StringVar(&baseURL, "base-url", "", "Base URL path for the API (e.g., /api/v1)")
 	flag.StringVar(&port, "port", "", "Port to listen on (default: 8080 or PORT env var)")
+	flag.BoolVar(&deterministic, "deterministic", false, "Use sequential IDs, seeded hashes and a frozen clock")
+	flag.Int64Var(&seed, "seed", 1, "Seed for hashes in deterministic mode")
+	flag.StringVar(&clockStart, "clock-start", "2026-01-01T00:00:00Z", "Initial clock time in deterministic mode (RFC3339)")
+	flag.IntVar(&hashLength, "hash-length", idgen.DefaultHashLength, "Length of public board hashes")
+	flag.DurationVar(&hubConfig.AuthCheckInterval, "ws-auth-check", hubConfig.AuthCheckInterval, "Interval for re-checking WebSocket permissions (0 - only on messages)")
+	flag.DurationVar(&hubConfig.PongWait, "ws-pong-wait", hubConfig.PongWait, "Time allowed to read the next pong from a WebSocket client")
+	flag.DurationVar(&hubConfig.PingPeriod, "ws-ping-period", hubConfig.PingPeriod, "Interval between WebSocket pings (must be less than -ws-pong-wait)")
+	flag.DurationVar(&hubConfig.WriteWait, "ws-write-wait", hubConfig.WriteWait, "Time allowed to write a WebSocket frame")
+	flag.Int64Var(&hubConfig.MaxMessageSize, "ws-max-message-size", hubConfig.MaxMessageSize, "Maximum size of an incoming WebSocket message in bytes")
+	flag.IntVar(&hubConfig.SendBufferSize, "ws-send-buffer", hubConfig.SendBufferSize, "Outgoing queue length per WebSocket client before it is dropped as slow")
+	flag.IntVar(&hubConfig.MaxSpectators, "max-spectators", hubConfig.MaxSpectators, "Maximum anonymous WebSocket spectators per board (0 - unlimited)")
+	flag.BoolVar(&hubConfig.Compression, "ws-compression", false, "Enable permessage-deflate compression for WebSocket clients that offer it")
+	flag.IntVar(&hubConfig.EventHistorySize, "sse-history", hubConfig.EventHistorySize, "Number of recent board events kept for SSE resume via Last-Event-ID (0 - disabled)")
+	flag.DurationVar(&hubConfig.KeepAliveInterval, "sse-keepalive", hubConfig.KeepAliveInterval, "Interval between SSE keep-alive comments")
+	flag.StringVar(&brokerURL, "broker", "memory", "Pub/sub broker for board events: memory or redis://[:password@]host:port")
+	flag.StringVar(&brokerPrefix, "broker-prefix", broker.DefaultChannelPrefix, "Channel name prefix for board events in the broker")
+	flag.StringVar(&assetsDir, "assets-dir", "data/assets", "Directory for uploaded images")
+	flag.DurationVar(&assetGCInterval, "asset-gc-interval", 10*time.Minute, "Interval between removals of images no object refers to (0 - disabled; POST /_assets/gc works with -deterministic)")
+	flag.DurationVar(&assetGCGrace, "asset-gc-grace", time.Hour, "Minimum age of an unused image before it is removed")
 	flag.Parse()
 
+	if err := hubConfig.Validate(); err != nil {
+		log.Fatal(err)
+	}
+
+	if hashLength < idgen.MinHashLength || hashLength > idgen.MaxHashLength {
+		log.Fatalf("-hash-length must be between %d and %d", idgen.MinHashLength, idgen.MaxHashLength)
+	}
+	idgen.SetDefault(idgen.NewRandom(hashLength))
+
+	// Детерминированный режим для снапшот-тестов
+	if deterministic {
+		start, err := time.Parse(time.RFC3339, clockStart)
+		if err != nil {
+			log.Fatalf("invalid -clock-start: %v", err)
+		}
+		idgen.SetDefault(idgen.NewSequential(seed, hashLength))
+		hubConfig.EventEpoch = fmt.Sprintf("seed%d", seed)
+		clock.Default().Freeze()
+		clock.Default().Set(start)
+	}
+
 	// Нормализация base URL
 	if baseURL != "" {
 		baseURL = strings.TrimSuffix(baseURL, "/")
@@ -33,11 +116,46 @@ func main() {
 		}
 	}
 
-	// Инициализация хранилища
-	store := storage.NewMemoryStorage()
+	// Инициализация хранилища: изменения досок и объектов сразу попадают в поисковый индекс
+	store := search.NewIndexedStorage(storage.NewMemoryStorage())
+
+	// Брокер событий: в памяти для одного экземпляра, Redis - для нескольких реплик
+	if brokerURL != "memory" {
+		b, err := broker.NewRedis(brokerURL, brokerPrefix)
+		if err != nil {
+			log.Fatalf("broker: %v", err)
+		}
+		defer b.Close()
+		hubConfig.Broker = b
+		if u, err := url.Parse(brokerURL); err == nil {
+			log.Printf("Using broker %s", u.Redacted())
+		}
+	}
+
+	// Загруженные изображения хранятся на диске, неиспользуемые периодически удаляются
+	files, err := assets.NewStore(assetsDir)
+	if err != nil {
+		log.Fatalf("assets: %v", err)
+	}
+	orphans, err := files.Prune(func(id string) bool {
+		_, err := store.GetAsset(id)
+		return err == nil
+	})
+	switch {
+	case errors.Is(err, assets.ErrForeignDir):
+		log.Printf("assets: %s was not created by the server, images left from previous runs are kept", assetsDir)
+	case err != nil:
+		log.Fatalf("assets: %v", err)
+	}
+	if len(orphans) > 0 {
+		log.Printf("Removed %d images left from a previous run", len(orphans))
+	}
+	if assetGCInterval > 0 {
+		go api.RunAssetCollector(store, files, assetGCInterval, assetGCGrace)
+	}
 
 	// Инициализация Hub для WebSocket
-	hub := api.NewHub(store)
+	hub := api.NewHub(store, hubConfig)
 	go hub.Run()
 
 	// Создание роутера
@@ -84,20 +202,88 @@ func main() {
 	apiRouter.HandleFunc("/authorization", api.Authorization(store)).Methods("POST", "OPTIONS")
 	apiRouter.HandleFunc("/public-boards", api.GetPublicBoards(store)).Methods("GET", "OPTIONS")
 	apiRouter.HandleFunc("/board/{hash}", api.GetBoardByHash(store)).Methods("GET", "OPTIONS")
+	apiRouter.HandleFunc("/templates", api.GetTemplates(store)).Methods("GET", "OPTIONS")
+	apiRouter.HandleFunc("/search", api.Search(store.Index, store)).Methods("GET", "OPTIONS")
+	// Служебные маршруты открыты без токена: генерация создает аккаунты с известным паролем,
+	// часы меняют сроки токенов и приглашений, поэтому все они доступны только в тестовом режиме
+	if deterministic {
+		apiRouter.HandleFunc("/_generate", api.Generate(store)).Methods("POST", "OPTIONS")
+		apiRouter.HandleFunc("/_clock", api.GetClock()).Methods("GET", "OPTIONS")
+		apiRouter.HandleFunc("/_clock", api.SetClock()).Methods("POST", "OPTIONS")
+		apiRouter.HandleFunc("/_assets/gc", api.CollectAssets(store, files, assetGCGrace)).Methods("POST", "OPTIONS")
+	}
+	apiRouter.HandleFunc("/_metrics", api.GetMetrics(hub)).Methods("GET", "OPTIONS")
+
+	// Файлы изображений открываются без токена: <img> не передает заголовки
+	apiRouter.HandleFunc("/assets/{asset_id}", api.ServeAsset(store, files)).Methods("GET", "HEAD", "OPTIONS")
+	apiRouter.HandleFunc("/assets/{asset_id}/{variant}", api.ServeAsset(store, files)).Methods("GET", "HEAD", "OPTIONS")
+
+	// SSE-поток событий доски: токен проверяется в обработчике, т.к. EventSource не передает заголовки
+	apiRouter.HandleFunc("/boards/{board_id}/events", api.ServeEvents(hub, store)).Methods("GET", "OPTIONS")
 
 	// Защищенные эндпоинты
 	protected := apiRouter.PathPrefix("").Subrouter()
 	protected.Use(middleware.AuthMiddleware(store))
 
 	// Добавляем OPTIONS методы для всех защищенных эндпоинтов
-	protected.HandleFunc("/logout", api.Logout(store)).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/logout", api.Logout(hub, store)).Methods("GET", "OPTIONS")
 	protected.HandleFunc("/boards", api.CreateBoard(store)).Methods("POST", "OPTIONS")
 	protected.HandleFunc("/boards", api.GetUserBoards(store)).Methods("GET", "OPTIONS")
-	protected.HandleFunc("/boards/{board_id}/share", api.ShareBoard(store)).Methods("POST", "OPTIONS")
-	protected.HandleFunc("/boards/{board_id}/like", api.LikeBoard(store)).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/share", api.ShareBoard(hub, store)).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/share", api.GetBoardMembers(store)).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/share/{user_id}", api.UnshareBoard(hub, store)).Methods("DELETE", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/like", api.LikeBoard(hub, store)).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/tags", api.SetBoardTags(store)).Methods("PUT", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/folder", api.SetBoardFolder(store)).Methods("PUT", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/favorite", api.SetBoardFavorite(store, true)).Methods("PUT", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/favorite", api.SetBoardFavorite(store, false)).Methods("DELETE", "OPTIONS")
+	protected.HandleFunc("/folders", api.GetFolders(store)).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/folders", api.CreateFolder(store)).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/folders/{folder_id}", api.UpdateFolder(store)).Methods("PATCH", "OPTIONS")
+	protected.HandleFunc("/folders/{folder_id}", api.DeleteFolder(store)).Methods("DELETE", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/invites", api.CreateInvite(store)).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/invites", api.GetBoardInvites(store)).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/invites/{token}", api.RevokeInvite(store)).Methods("DELETE", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/objects", api.ListBoardObjects(store)).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/objects", api.CreateBoardObject(hub, store)).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/objects/reorder", api.ReorderBoardObjects(hub, store)).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/objects/group", api.GroupBoardObjects(hub, store)).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/objects/{object_id}", api.GetBoardObject(store)).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/objects/{object_id}", api.PatchBoardObject(hub, store)).Methods("PATCH", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/objects/{object_id}", api.DeleteBoardObject(hub, store)).Methods("DELETE", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/objects/{object_id}/ungroup", api.UngroupBoardObject(hub, store)).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/objects/{object_id}/lock", api.LockBoardObject(hub)).Methods("PUT", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/assets", api.GetBoardAssets(store)).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/assets", api.UploadAsset(store, files)).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/layers", api.GetLayers(store)).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/layers", api.CreateLayer(hub, store)).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/layers/{layer_id}", api.UpdateLayer(hub, store)).Methods("PATCH", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/layers/{layer_id}", api.DeleteLayer(hub, store)).Methods("DELETE", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/objects/{object_id}/comments", api.GetObjectComments(store)).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/objects/{object_id}/comments", api.PostObjectComment(hub, store)).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/comments/{comment_id}", api.EditComment(hub, store)).Methods("PATCH", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/comments/{comment_id}", api.DeleteComment(hub, store)).Methods("DELETE", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/chat", api.GetChatMessages(store)).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/chat", api.PostChatMessage(hub, store)).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/chat/{message_id}", api.EditChatMessage(hub, store)).Methods("PATCH", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/chat/{message_id}", api.DeleteChatMessage(hub, store)).Methods("DELETE", "OPTIONS")
+	protected.HandleFunc("/notifications", api.GetNotifications(store)).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/notifications/read", api.MarkNotificationsRead(hub)).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/invites/{token}/redeem", api.RedeemInvite(store)).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/link", api.SetBoardLink(hub, store)).Methods("PATCH", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/template", api.SetBoardTemplate(store)).Methods("PATCH", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/duplicate", api.DuplicateBoard(store)).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/snapshots", api.GetSnapshots(store)).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/snapshots", api.CreateSnapshot(store)).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/snapshots/diff", api.DiffSnapshots(store)).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/snapshots/{snapshot_id:[0-9]+}", api.GetSnapshot(store)).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/snapshots/{snapshot_id:[0-9]+}/restore", api.RestoreSnapshot(hub, store)).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/boards/{board_id}/link/regenerate", api.RegenerateBoardLink(hub, store)).Methods("POST", "OPTIONS")
 
 	// WebSocket
 	apiRouter.HandleFunc("/ws/board/{board_id}", api.ServeWs(hub, store))
+	apiRouter.HandleFunc("/ws/public/{hash}", api.ServePublicWs(hub, store))
+	apiRouter.HandleFunc("/ws/notifications", api.ServeNotificationsWs(hub, store))
 
 	// Получение порта из аргумента командной строки или переменной окружения
 	if port == "" {
@@ -107,13 +293,122 @@ func main() {
 		}
 	}
 
+	if deterministic {
+		log.Printf("Deterministic mode: seed %d, clock frozen at %s", seed, clock.Now().Format(time.RFC3339))
+	}
+
 	if baseURL != "" {
 		log.Printf("Server starting on port %s with base URL %s...", port, baseURL)
 	} else {
 		log.Printf("Server starting on port %s...", port)
 	}
 	
-	if err := http.ListenAndServe(":"+port, r); err != nil {
+	server := &http.Server{Addr: ":" + port, Handler: r}
+
+	// Корректная остановка: закрываем WebSocket с кодом 1001 и дожидаемся HTTP-запросов
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	// ListenAndServe возвращается сразу после начала Shutdown, поэтому main ждет done,
+	// чтобы не завершиться раньше обработки текущих запросов
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		<-stop
+		log.Println("Shutting down...")
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := hub.Shutdown(ctx); err != nil {
+			log.Printf("WebSocket shutdown: %v", err)
+		}
+		if err := server.Shutdown(ctx); err != nil {
+			log.Printf("HTTP shutdown: %v", err)
+		}
+	}()
+
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+	<-done
+	log.Println("Server stopped")
+}
+
+// runGenerate генерирует пользователей и доски в пустом хранилище и печатает их в stdout в JSON
+func runGenerate(args []string) {
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	users := fs.Int("users", 10, "Number of users to generate")
+	boardsPerUser := fs.Int("boards-per-user", 3, "Maximum number of boards per user")
+	objectsPerBoard := fs.Int("objects-per-board", 12, "Maximum number of objects per board")
+	seed := fs.Int64("seed", 1, "Seed for the random generator")
+	fs.Parse(args)
+
+	store := storage.NewMemoryStorage()
+	result, err := generator.Generate(store, generator.Options{
+		Users:           *users,
+		BoardsPerUser:   *boardsPerUser,
+		ObjectsPerBoard: *objectsPerBoard,
+		Seed:            seed,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// В выгрузку попадают доски целиком, вместе с объектами
+	boards := make([]*models.Board, 0, len(result.Boards))
+	for _, b := range result.Boards {
+		board, err := store.GetBoardByID(b.ID)
+		if err != nil {
+			log.Fatal(err)
+		}
+		boards = append(boards, board)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(map[string]interface{}{
+		"seed":   result.Seed,
+		"users":  result.Users,
+		"boards": boards,
+	}); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// runBroker запускает встроенный pub/sub сервер, совместимый с Redis
+func runBroker(args []string) {
+	fs := flag.NewFlagSet("broker", flag.ExitOnError)
+	addr := fs.String("addr", ":6379", "Address to listen on")
+	fs.Parse(args)
+
+	log.Printf("Broker listening on %s...", *addr)
+	if err := broker.NewServer().ListenAndServe(*addr); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// runHubBench запускает нагрузочный тест Hub и печатает результат в JSON
+func runHubBench(args []string) {
+	fs := flag.NewFlagSet("hub-bench", flag.ExitOnError)
+	opts := api.HubBenchOptions{}
+	config := api.DefaultHubConfig()
+	fs.IntVar(&opts.Boards, "boards", 200, "Number of boards")
+	fs.IntVar(&opts.Clients, "clients", 5000, "Number of clients spread evenly across boards")
+	fs.IntVar(&opts.Messages, "messages", 100000, "Total number of broadcasts")
+	fs.IntVar(&opts.Senders, "senders", 16, "Number of concurrent senders")
+	fs.StringVar(&opts.Format, "format", api.FormatJSON, "Message format of the clients: json or msgpack")
+	// Рассылка идет пачкой без пауз, поэтому очередь больше, чем у сервера: иначе
+	// замер покажет отключение медленных читателей, а не пропускную способность Hub
+	config.SendBufferSize = 4096
+	fs.IntVar(&config.SendBufferSize, "send-buffer", config.SendBufferSize, "Outgoing queue length per client (clients that overflow it are dropped)")
+	fs.Parse(args)
+
+	result, err := api.BenchmarkHub(config, opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(result); err != nil {
 		log.Fatal(err)
 	}
 }